@@ -0,0 +1,83 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/nvidia/container-canary/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD NEW",
+	Short: "Compare two JSON validation reports",
+	Long: `Compare two reports written with 'canary validate --output json', for example
+from the previous and next release of an image. Each check is classified as
+regressed, fixed, unchanged, new or removed. The command fails only if a check
+which passed in the old report does not pass in the new one, or the new
+validation didn't finish.`,
+	Args:         exactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldReport, err := readReport(args[0])
+		if err != nil {
+			return err
+		}
+		newReport, err := readReport(args[1])
+		if err != nil {
+			return err
+		}
+		comparison := report.Compare(oldReport, newReport)
+		if err := comparison.Write(cmd.OutOrStdout()); err != nil {
+			return err
+		}
+		return regressionError(comparison)
+	},
+}
+
+// readReport loads a JSON report from a file
+func readReport(path string) (*report.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.ConfigError, err)
+	}
+	defer f.Close()
+	r, err := report.ReadJSON(f)
+	if err != nil {
+		return nil, exitcode.Errorf(exitcode.ConfigError, "%s is not a JSON report: %w", path, err)
+	}
+	return r, nil
+}
+
+// regressionError fails when a check regressed, or when the new validation
+// didn't finish and so can't show that nothing did
+func regressionError(c *report.Comparison) error {
+	if c.New.Error != "" {
+		return exitcode.Errorf(exitcode.ChecksFailed, "validation of %s did not finish: %s", c.New.Image.Name, c.New.Error)
+	}
+	if n := c.Count(report.ChangeRegressed); n > 0 {
+		return exitcode.Errorf(exitcode.ChecksFailed, "%d checks regressed", n)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,99 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/nvidia/container-canary/internal/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestReport(t *testing.T, name string, statuses ...report.Status) string {
+	r := &report.Report{
+		SchemaVersion: report.SchemaVersion,
+		Validator:     report.Validator{Name: "kubeflow"},
+		Image:         report.Image{Name: name},
+	}
+	for i, status := range statuses {
+		r.Checks = append(r.Checks, report.Check{Name: string(rune('a' + i)), Status: status})
+	}
+	return writeReportFile(t, r)
+}
+
+func writeReportFile(t *testing.T, r *report.Report) string {
+	path := filepath.Join(t.TempDir(), "report.json")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := r.Write("json", f); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+	b := new(bytes.Buffer)
+	rootCmd.SetOut(b)
+	rootCmd.SetErr(b)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+	})
+
+	old := writeTestReport(t, "base:1.0", report.StatusPassed, report.StatusFailed)
+	fixed := writeTestReport(t, "base:1.1", report.StatusPassed, report.StatusPassed)
+	rootCmd.SetArgs([]string{"diff", old, fixed})
+	err := rootCmd.Execute()
+	assert.Nil(err)
+	assert.Contains(b.String(), "1 fixed, 1 unchanged")
+
+	b.Reset()
+	regressed := writeTestReport(t, "base:1.1", report.StatusError, report.StatusFailed)
+	rootCmd.SetArgs([]string{"diff", old, regressed})
+	err = rootCmd.Execute()
+	assert.Equal(exitcode.ChecksFailed, exitcode.Of(err))
+	assert.Contains(b.String(), "1 regressed, 1 unchanged")
+
+	// A new run which failed is never a pass, even with no checks to regress
+	b.Reset()
+	failed := writeReportFile(t, &report.Report{
+		SchemaVersion: report.SchemaVersion,
+		Image:         report.Image{Name: "base:1.1"},
+		Checks:        []report.Check{},
+		Error:         "no such file kubeflow.yaml",
+	})
+	rootCmd.SetArgs([]string{"diff", old, failed})
+	err = rootCmd.Execute()
+	assert.Equal(exitcode.ChecksFailed, exitcode.Of(err))
+	assert.Contains(b.String(), "did not finish")
+
+	rootCmd.SetArgs([]string{"diff", old})
+	err = rootCmd.Execute()
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+
+	rootCmd.SetArgs([]string{"diff", old, "../examples/kubeflow.yaml"})
+	err = rootCmd.Execute()
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+}
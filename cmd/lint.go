@@ -0,0 +1,77 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/nvidia/container-canary/internal/config"
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint FILE...",
+	Short: "Check validator manifests for mistakes",
+	Long: `Check validator manifests without needing Docker or an image. Anything that
+would stop a manifest from loading is an error, likely mistakes such as checks
+without descriptions or probes on ports which aren't published are warnings.`,
+	Args:         minimumArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		strict, err := cmd.Flags().GetBool("strict")
+		if err != nil {
+			return err
+		}
+
+		errors, warnings := 0, 0
+		for _, path := range args {
+			findings, err := config.LintFile(path)
+			if err != nil {
+				return err
+			}
+			for _, finding := range findings {
+				fmt.Fprintln(cmd.OutOrStdout(), finding.String())
+				if finding.Level == config.LevelError {
+					errors++
+				} else {
+					warnings++
+				}
+			}
+		}
+		if errors+warnings > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s, %s\n", plural(errors, "error"), plural(warnings, "warning"))
+		}
+		if errors > 0 || (strict && warnings > 0) {
+			return exitcode.Errorf(exitcode.ConfigError, "lint failed")
+		}
+		return nil
+	},
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().Bool("strict", false, "Fail if there are warnings as well as errors.")
+}
@@ -0,0 +1,65 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	assert := assert.New(t)
+	b := new(bytes.Buffer)
+	rootCmd.SetOut(b)
+	rootCmd.SetErr(b)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		_ = lintCmd.Flags().Set("strict", "false")
+	})
+
+	rootCmd.SetArgs([]string{"lint", "../examples/kubeflow.yaml", "../examples/databricks.yaml"})
+	err := rootCmd.Execute()
+	assert.Nil(err)
+	assert.Empty(b.String())
+
+	path := filepath.Join(t.TempDir(), "validator.yaml")
+	err = os.WriteFile(path, []byte("apiVersion: container-canary.nvidia.com/v1\nkind: Validator\nname: foo\nchecks:\n  - name: bar\n    probe:\n      exec:\n        command: [\"true\"]\n"), 0644)
+	assert.Nil(err)
+	rootCmd.SetArgs([]string{"lint", path})
+	err = rootCmd.Execute()
+	assert.Nil(err)
+	assert.Contains(b.String(), "warning: check 'bar' has no description")
+	assert.Contains(b.String(), "0 errors, 1 warning\n")
+
+	rootCmd.SetArgs([]string{"lint", "--strict", path})
+	err = rootCmd.Execute()
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+
+	b.Reset()
+	err = os.WriteFile(path, []byte("name: foo\n"), 0644)
+	assert.Nil(err)
+	rootCmd.SetArgs([]string{"lint", path})
+	err = rootCmd.Execute()
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(b.String(), "error: ")
+}
@@ -0,0 +1,41 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestList(t *testing.T) {
+	assert := assert.New(t)
+	b := new(bytes.Buffer)
+	rootCmd.SetOut(b)
+	rootCmd.SetErr(b)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+	})
+
+	rootCmd.SetArgs([]string{"list"})
+	err := rootCmd.Execute()
+	assert.Nil(err)
+	assert.Regexp(`^NAME +DESCRIPTION +DOCUMENTATION\n`, b.String())
+	assert.Regexp(`\nkubeflow +Kubeflow notebooks +https://www.kubeflow.org/docs/components/notebooks/container-images/#custom-images\n`, b.String())
+}
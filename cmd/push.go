@@ -0,0 +1,53 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/nvidia/container-canary/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var pushCmd = &cobra.Command{
+	Use:   "push FILE oci://REGISTRY/REPOSITORY:TAG",
+	Short: "Publish a validator manifest to an OCI registry",
+	Long: `Publish a validator manifest to a registry as an OCI artifact, so that it can be
+versioned and access controlled like images and validated against with
+--file oci://REGISTRY/REPOSITORY:TAG. Credentials are the same as Docker's,
+use 'docker login' to log in to the registry first.`,
+	Args:         exactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		signature, err := cmd.Flags().GetString("signature")
+		if err != nil {
+			return err
+		}
+		reference, err := config.PushValidator(args[0], args[1], signature)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Pushed %s\n", reference)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pushCmd)
+	pushCmd.Flags().String("signature", "", "minisign signature of the manifest to push with it, for validating with --public-key.")
+}
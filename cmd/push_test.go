@@ -0,0 +1,53 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPush(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+	location := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/validators/kubeflow:1.0"
+	b := new(bytes.Buffer)
+	rootCmd.SetOut(b)
+	rootCmd.SetErr(b)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+	})
+
+	rootCmd.SetArgs([]string{"push", "../examples/kubeflow.yaml", location})
+	err := rootCmd.Execute()
+	assert.Nil(err)
+	assert.Regexp(`^Pushed oci://.*/validators/kubeflow@sha256:[0-9a-f]{64}\n$`, b.String())
+
+	rootCmd.SetArgs([]string{"push", "../examples/kubeflow.yaml"})
+	err = rootCmd.Execute()
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+}
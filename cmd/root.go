@@ -20,6 +20,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/nvidia/container-canary/internal/exitcode"
 	"github.com/spf13/cobra"
 )
 
@@ -33,6 +34,7 @@ for customization. Container Conairy validates whether your container images wil
 be compatible with a range of platforms.
 
 Example:
+$ canary list
 $ canary validate kubeflow myorg/mycontainer:rev
 
 `,
@@ -43,7 +45,21 @@ $ canary validate kubeflow myorg/mycontainer:rev
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(int(exitcode.Of(err)))
+	}
+}
+
+// exactArgs is cobra.ExactArgs with a usage exit code
+func exactArgs(n int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		return exitcode.Wrap(exitcode.ConfigError, cobra.ExactArgs(n)(cmd, args))
+	}
+}
+
+// minimumArgs is cobra.MinimumNArgs with a usage exit code
+func minimumArgs(n int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		return exitcode.Wrap(exitcode.ConfigError, cobra.MinimumNArgs(n)(cmd, args))
 	}
 }
 
@@ -57,4 +73,8 @@ func init() {
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return exitcode.Wrap(exitcode.ConfigError, err)
+	})
 }
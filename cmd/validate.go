@@ -18,73 +18,228 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/nvidia/container-canary/internal/config"
 	"github.com/nvidia/container-canary/internal/container"
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/nvidia/container-canary/internal/report"
+	"github.com/nvidia/container-canary/internal/tracing"
 	"github.com/nvidia/container-canary/internal/validator"
 	"github.com/spf13/cobra"
 )
 
 var validateCmd = &cobra.Command{
-	Use:           "validate [IMAGE]",
-	Short:         "Validate a container against a platform",
-	Long:          ``,
-	Args:          imageArg,
-	SilenceUsage:  true,
-	SilenceErrors: false,
+	Use:   "validate [PLATFORM] IMAGE",
+	Short: "Validate a container against a platform",
+	Long:  ``,
+	Args: func(cmd *cobra.Command, args []string) error {
+		return printError(cmd, imageArg(cmd, args))
+	},
+	SilenceUsage: true,
+	// The reporter shows validation errors, so the rest are printed by printError
+	SilenceErrors: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		file, err := cmd.Flags().GetString("file")
-		if err != nil {
+		err := runValidate(cmd, args)
+		var shown shownError
+		if errors.As(err, &shown) {
+			return shown.error
+		}
+		return printError(cmd, err)
+	},
+}
+
+// shownError is an error which the reporter has already shown
+type shownError struct {
+	error
+}
+
+func (e shownError) Unwrap() error {
+	return e.error
+}
+
+// printError shows an error the way cobra would if it wasn't silenced
+func printError(cmd *cobra.Command, err error) error {
+	if err != nil {
+		cmd.PrintErrln("Error:", err.Error())
+	}
+	return err
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	file, err := manifestLocation(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	image := args[len(args)-1]
+	debug, err := cmd.Flags().GetBool("debug")
+	if err != nil {
+		return err
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+	if output != "" && !slices.Contains(report.Formats(), output) {
+		return exitcode.Errorf(exitcode.ConfigError, "unknown output format '%s', must be one of %s", output, strings.Join(report.Formats(), ", "))
+	}
+	outputFile, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return err
+	}
+	junitFile, err := cmd.Flags().GetString("junit")
+	if err != nil {
+		return err
+	}
+	dockerfile, err := cmd.Flags().GetString("dockerfile")
+	if err != nil {
+		return err
+	}
+
+	baselineFile, err := cmd.Flags().GetString("baseline")
+	if err != nil {
+		return err
+	}
+	var baseline *report.Report
+	if baselineFile != "" {
+		if baseline, err = readReport(baselineFile); err != nil {
 			return err
 		}
-		if file == "" {
-			return errors.New("you must specify a manifest with '--file path/url'")
+	}
+
+	traceFile, err := cmd.Flags().GetString("trace-file")
+	if err != nil {
+		return err
+	}
+	shutdownTracing, err := tracing.Setup(cmd.Context(), traceFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			cmd.PrintErrf("Failed to export traces: %s\n", err.Error())
 		}
+	}()
 
-		image := args[0]
-		debug, err := cmd.Flags().GetBool("debug")
-		if err != nil {
+	r, err := validator.Validate(image, file, cmd, debug)
+	if r != nil {
+		r.Image.Dockerfile = dockerfile
+	}
+	if r != nil && output != "" {
+		if err := writeReport(cmd, r, output, outputFile); err != nil {
 			return err
 		}
-
-		v, err := validator.Validate(image, file, cmd, debug)
-		if err != nil {
-			cmd.Printf("Error: %s\n", err.Error())
+	}
+	if r != nil && junitFile != "" {
+		if err := writeReport(cmd, r, "junit", junitFile); err != nil {
 			return err
 		}
-		if !v {
-			return errors.New("validation failed")
+	}
+	// Validate only returns a report once the reporter is running, and it shows
+	// any error from then on
+	if err != nil && r != nil {
+		err = shownError{err}
+	}
+	if err != nil && !errors.Is(err, validator.ErrValidationFailed) {
+		return err
+	}
+	if baseline != nil {
+		comparison := report.Compare(baseline, r)
+		if err := comparison.Write(cmd.OutOrStderr()); err != nil {
+			return err
 		}
-		return nil
-	},
+		return regressionError(comparison)
+	}
+	return err
 }
 
-func imageArg(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return errors.New("requires an image argument")
+// manifestLocation finds the validator to use from --file, --platform or the
+// platform argument before the image
+func manifestLocation(cmd *cobra.Command, args []string) (string, error) {
+	file, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return "", err
 	}
-
-	if len(args) > 1 {
-		return errors.New("too many arguments")
+	platform, err := cmd.Flags().GetString("platform")
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 2 {
+		if platform != "" {
+			return "", exitcode.Errorf(exitcode.ConfigError, "give the platform either as an argument or with '--platform', not both")
+		}
+		platform = args[0]
+	}
+	if platform != "" {
+		if file != "" {
+			return "", exitcode.Errorf(exitcode.ConfigError, "'--file' can't be used with a platform")
+		}
+		return config.BuiltinLocation(platform), nil
+	}
+	if file == "" {
+		return "", exitcode.Errorf(exitcode.ConfigError, "you must specify a platform or a manifest with '--file path/url', see 'canary list' for platforms")
 	}
+	return file, nil
+}
 
-	if err := container.CheckForDocker(); err != nil {
+// writeReport writes the report to a file, or to stdout if no file is given
+func writeReport(cmd *cobra.Command, r *report.Report, format string, path string) error {
+	if path == "" || path == "-" {
+		return r.Write(format, cmd.OutOrStdout())
+	}
+	f, err := os.Create(path)
+	if err != nil {
 		return err
 	}
+	if err := r.Write(format, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
 
-	image := args[0]
+func imageArg(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return exitcode.Errorf(exitcode.ConfigError, "requires an image argument")
+	}
 
-	if validator.CheckImage(cmd, image, "docker") {
-		return nil
-	} else {
-		return fmt.Errorf("no such image: %s", image)
+	if len(args) > 2 {
+		return exitcode.Errorf(exitcode.ConfigError, "too many arguments")
 	}
+
+	return container.CheckForDocker()
 }
 
 func init() {
 	rootCmd.AddCommand(validateCmd)
-	validateCmd.PersistentFlags().String("file", "", "Path or URL of a manifest to validate against.")
+	validateCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return printError(cmd, exitcode.Wrap(exitcode.ConfigError, err))
+	})
+	validateCmd.PersistentFlags().String("file", "", "Path, URL or oci:// reference of a manifest to validate against.")
+	validateCmd.PersistentFlags().String("platform", "", "Name of a built in validator to validate against, see 'canary list'.")
+	validateCmd.PersistentFlags().StringArray("set", nil, "Set a parameter of the validator, as name=value. May be repeated.")
+	validateCmd.PersistentFlags().StringArray("values", nil, "YAML file of parameter names and values to set. May be repeated, --set takes precedence.")
+	validateCmd.PersistentFlags().String("public-key", "", "minisign public key that remote manifests must be signed with, their signatures are fetched from URL.minisig or pushed with 'canary push --signature'.")
+	validateCmd.PersistentFlags().StringSlice("only", nil, "Only run checks with these names or tags. May be repeated or comma separated.")
+	validateCmd.PersistentFlags().StringSlice("skip", nil, "Skip checks with these names or tags. May be repeated or comma separated.")
+	validateCmd.PersistentFlags().Bool("strict", false, "Treat checks with warning severity as errors, so that they fail validation.")
 	validateCmd.PersistentFlags().Bool("debug", false, "Keep container running on failure for debugging.")
 	validateCmd.PersistentFlags().Int("startup-timeout", 10, "Maximum time (in seconds) to wait for the container to start up.")
+	validateCmd.PersistentFlags().Bool("arbitrary-uid", false, "Run the container as a random UID with GID 0, as OpenShift does.")
+	validateCmd.PersistentFlags().String("reporter", validator.ReporterAuto, fmt.Sprintf("How to show progress (%s). auto uses plain output in CI or without a terminal.", strings.Join(validator.Reporters(), ", ")))
+	validateCmd.PersistentFlags().String("output", "", fmt.Sprintf("Write a report of the validation in this format (%s).", strings.Join(report.Formats(), ", ")))
+	validateCmd.PersistentFlags().String("output-file", "", "File to write the report to, defaults to stdout.")
+	validateCmd.PersistentFlags().String("junit", "", "Also write a JUnit XML report to this file.")
+	validateCmd.PersistentFlags().String("baseline", "", "JSON report of a previous validation, only fail if checks which passed then fail now.")
+	validateCmd.PersistentFlags().String("trace-file", "", "Write OpenTelemetry spans for the run to this file as JSON lines.")
+	validateCmd.PersistentFlags().String("dockerfile", "", "Path of the Dockerfile the image was built from, to attach report findings to.")
 }
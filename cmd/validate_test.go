@@ -19,8 +19,14 @@ package cmd
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/nvidia/container-canary/internal/report"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -46,9 +52,55 @@ func TestValidateFails(t *testing.T) {
 	err := rootCmd.Execute()
 
 	assert.NotNil(err, "should fail")
+	assert.Equal(exitcode.ChecksFailed, exitcode.Of(err))
 	assert.Contains(b.String(), "validation failed", "did not fail")
 }
 
+func TestValidateUsageExitCode(t *testing.T) {
+	assert := assert.New(t)
+	b := new(bytes.Buffer)
+	rootCmd.SetOut(b)
+	rootCmd.SetErr(b)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+	})
+
+	rootCmd.SetArgs([]string{"validate", "--file", "../examples/kubeflow.yaml"})
+	err := rootCmd.Execute()
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+
+	rootCmd.SetArgs([]string{"validate", "--no-such-flag", "container-canary/kubeflow:shouldpass"})
+	err = rootCmd.Execute()
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+}
+
+func TestValidateErrorShownOnce(t *testing.T) {
+	assert := assert.New(t)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	rootCmd.SetOut(stdout)
+	rootCmd.SetErr(stderr)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+	})
+
+	// Without Docker this fails checking the image, otherwise loading the
+	// manifest, which the reporter shows
+	rootCmd.SetArgs([]string{"validate", "--file", "does-not-exist.yaml", "container-canary/kubeflow:shouldpass"})
+	err := rootCmd.Execute()
+	if assert.NotNil(err) {
+		assert.Equal(1, strings.Count(stdout.String()+stderr.String(), err.Error()), stdout.String()+stderr.String())
+	}
+
+	rootCmd.SetArgs([]string{"validate", "--no-such-flag", "container-canary/kubeflow:shouldpass"})
+	stdout.Reset()
+	stderr.Reset()
+	err = rootCmd.Execute()
+	if assert.NotNil(err) {
+		assert.Equal(1, strings.Count(stdout.String()+stderr.String(), err.Error()))
+	}
+}
+
 func TestFileDoesNotExist(t *testing.T) {
 	assert := assert.New(t)
 	b := new(bytes.Buffer)
@@ -60,3 +112,66 @@ func TestFileDoesNotExist(t *testing.T) {
 	assert.NotNil(err, "did not error")
 	assert.Contains(b.String(), "Cannot find container-canary/kubeflow:doesnotexist", "did not fail")
 }
+
+func TestValidateJSONOutput(t *testing.T) {
+	assert := assert.New(t)
+	b := new(bytes.Buffer)
+	rootCmd.SetOut(b)
+	rootCmd.SetErr(b)
+	path := filepath.Join(t.TempDir(), "report.json")
+	rootCmd.SetArgs([]string{"validate", "--file", "../examples/kubeflow.yaml", "--output", "json", "--output-file", path, "container-canary/kubeflow:shouldfail"})
+	t.Cleanup(func() {
+		_ = validateCmd.PersistentFlags().Set("output", "")
+		_ = validateCmd.PersistentFlags().Set("output-file", "")
+	})
+	err := rootCmd.Execute()
+	assert.NotNil(err, "should fail")
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Errorf("Report was not written: %s", err.Error())
+		return
+	}
+	defer f.Close()
+	r, err := report.ReadJSON(f)
+	assert.Nil(err)
+	assert.Equal("kubeflow", r.Validator.Name)
+	assert.Equal("container-canary/kubeflow:shouldfail", r.Image.Name)
+	assert.False(r.Passed)
+	assert.Equal("user", r.Checks[0].Name)
+	assert.Equal(report.StatusFailed, r.Checks[0].Status)
+}
+
+func TestManifestLocation(t *testing.T) {
+	assert := assert.New(t)
+	resetFlags := func() {
+		_ = validateCmd.PersistentFlags().Set("file", "")
+		_ = validateCmd.PersistentFlags().Set("platform", "")
+	}
+	resetFlags()
+	t.Cleanup(resetFlags)
+
+	_, err := manifestLocation(validateCmd, []string{"example:latest"})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+
+	location, err := manifestLocation(validateCmd, []string{"kubeflow", "example:latest"})
+	assert.Nil(err)
+	assert.Equal("builtin:kubeflow", location)
+
+	assert.Nil(validateCmd.PersistentFlags().Set("platform", "binder"))
+	location, err = manifestLocation(validateCmd, []string{"example:latest"})
+	assert.Nil(err)
+	assert.Equal("builtin:binder", location)
+
+	_, err = manifestLocation(validateCmd, []string{"kubeflow", "example:latest"})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+
+	assert.Nil(validateCmd.PersistentFlags().Set("file", "validator.yaml"))
+	_, err = manifestLocation(validateCmd, []string{"example:latest"})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+
+	assert.Nil(validateCmd.PersistentFlags().Set("platform", ""))
+	location, err = manifestLocation(validateCmd, []string{"example:latest"})
+	assert.Nil(err)
+	assert.Equal("validator.yaml", location)
+}
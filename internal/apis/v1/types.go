@@ -19,8 +19,34 @@ package config
 
 import v1 "k8s.io/api/core/v1"
 
+// The apiVersion and kind every manifest must declare
+const (
+	APIVersion    = "container-canary.nvidia.com/v1"
+	ValidatorKind = "Validator"
+)
+
 // Validator contains validator specification
 type Validator struct {
+	// The version of the manifest schema, container-canary.nvidia.com/v1.
+	APIVersion string `yaml:"apiVersion"`
+
+	// The kind of manifest, always Validator.
+	Kind string `yaml:"kind"`
+
+	// A parent validator to inherit everything from. Settings in this
+	// manifest override the parent's.
+	// +optional
+	Extends string `yaml:"extends,omitempty"`
+
+	// Other validators to add the checks, env, ports and volumes of.
+	// +optional
+	Include []string `yaml:"include,omitempty"`
+
+	// Parameters and their default values, referenced elsewhere in the
+	// manifest as ${{ params.name }}.
+	// +optional
+	Parameters map[string]string `yaml:"parameters,omitempty"`
+
 	// The validator name.
 	// +optional
 	Name string
@@ -51,7 +77,23 @@ type Validator struct {
 
 	// Additional flags to pass to the docker CLI.
 	// +optional
-	DockerRunOptions []string  `yaml:"dockerRunOptions"`
+	DockerRunOptions []string `yaml:"dockerRunOptions"`
+
+	// Security options to run the container with.
+	// +optional
+	SecurityContext *SecurityContext `yaml:"securityContext,omitempty"`
+}
+
+type SecurityContext struct {
+	// Start the container with a read-only root filesystem. Volumes, including
+	// tmpfs volumes, are still writable.
+	// +optional
+	ReadOnlyRootFilesystem bool `yaml:"readOnlyRootFilesystem,omitempty"`
+
+	// Run the container as a random high UID with GID 0, as the OpenShift
+	// restricted SCC does.
+	// +optional
+	RunAsArbitraryUser bool `yaml:"runAsArbitraryUser,omitempty"`
 }
 
 type Check struct {
@@ -63,6 +105,16 @@ type Check struct {
 	// +optional
 	Description string
 
+	// Tags group checks, so that --only and --skip can select them by tag
+	// as well as by name.
+	// +optional
+	Tags []string
+
+	// How much it matters if the check doesn't pass, one of error, warning
+	// or info. Only errors fail validation. Defaults to error.
+	// +optional
+	Severity string
+
 	// A probe to run.
 	Probe Probe
 }
@@ -85,6 +137,16 @@ type Probe struct {
 	HTTPGet *HTTPGetAction `yaml:"httpGet"`
 
 	TCPSocket *TCPSocketAction `yaml:"tcpSocket" `
+
+	ImageSize *ImageSizeAction `yaml:"imageSize"`
+
+	Packages *PackagesAction `yaml:"packages"`
+
+	Security *SecurityAction `yaml:"security"`
+
+	ReadOnlyRootFilesystem *ReadOnlyRootFilesystemAction `yaml:"readOnlyRootFilesystem"`
+
+	GroupWritable *GroupWritableAction `yaml:"groupWritable"`
 }
 
 func (p *Probe) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -130,6 +192,71 @@ type TCPSocketAction struct {
 	Port int `yaml:"port"`
 }
 
+type ImageSizeAction struct {
+	// Maximum uncompressed size of the image.
+	// +optional
+	MaxSize ByteSize `yaml:"maxSize,omitempty"`
+	// Maximum compressed size of the image, estimated by gzipping the layers
+	// as a registry push would.
+	// +optional
+	MaxCompressedSize ByteSize `yaml:"maxCompressedSize,omitempty"`
+	// Maximum number of filesystem layers in the image.
+	// +optional
+	MaxLayers int `yaml:"maxLayers,omitempty"`
+	// Maximum uncompressed size of any single layer.
+	// +optional
+	MaxLayerSize ByteSize `yaml:"maxLayerSize,omitempty"`
+}
+
+type PackagesAction struct {
+	// Package manager to query, one of dpkg, apk, rpm, pip or conda.
+	// Every package manager found in the container is queried if omitted.
+	// +optional
+	Manager string `yaml:"manager,omitempty"`
+	// Packages which must be installed, optionally with a version constraint
+	// such as python>=3.9.
+	Require []string `yaml:"require"`
+}
+
+// Severities of a check
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Rules supported by the security probe
+const (
+	SecurityRuleNoSetuid        = "noSetuid"
+	SecurityRuleNoWorldWritable = "noWorldWritable"
+	SecurityRuleNonRootPid1     = "nonRootPid1"
+	SecurityRuleNoSecrets       = "noSecrets"
+	SecurityRuleNoSSHServer     = "noSSHServer"
+)
+
+type SecurityAction struct {
+	// The rule to enforce, one of noSetuid, noWorldWritable, nonRootPid1,
+	// noSecrets or noSSHServer.
+	Rule string `yaml:"rule"`
+	// Paths which are exempt from the rule. Entries ending in '/' exempt
+	// everything beneath them, other entries may be glob patterns.
+	// +optional
+	Allow []string `yaml:"allow,omitempty"`
+}
+
+type ReadOnlyRootFilesystemAction struct {
+	// Paths which may be written to in addition to volumes. Entries ending
+	// in '/' allow everything beneath them, other entries may be glob patterns.
+	// +optional
+	Allow []string `yaml:"allow,omitempty"`
+}
+
+type GroupWritableAction struct {
+	// Paths which must be writable by GID 0, in addition to the home directory.
+	// +optional
+	Paths []string `yaml:"paths,omitempty"`
+}
+
 type Volume struct {
 	// Path to mount in the container
 	MountPath string `yaml:"mountPath,omitempty"`
@@ -137,4 +264,8 @@ type Volume struct {
 	// Path to mount from host, will use empty volume if omitted
 	// +optional
 	Path string `yaml:"path,omitempty"`
+
+	// Mount an in-memory tmpfs rather than an empty volume
+	// +optional
+	Tmpfs bool `yaml:"tmpfs,omitempty"`
 }
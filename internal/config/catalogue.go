@@ -0,0 +1,87 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+
+	"github.com/nvidia/container-canary/examples"
+	"github.com/nvidia/container-canary/internal/exitcode"
+)
+
+// BuiltinPrefix marks the location of a validator built into canary, such as builtin:kubeflow
+const BuiltinPrefix = "builtin:"
+
+// Platform describes a validator built into canary
+type Platform struct {
+	Name          string
+	Description   string
+	Documentation string
+}
+
+// BuiltinLocation is the location to load a built in validator from
+func BuiltinLocation(name string) string {
+	return BuiltinPrefix + name
+}
+
+func isBuiltin(location string) bool {
+	return strings.HasPrefix(location, BuiltinPrefix)
+}
+
+// builtinName is the name of a built in validator, without the prefix or extension
+func builtinName(location string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(location, BuiltinPrefix), ".yaml")
+}
+
+// PlatformNames lists the names of the built in validators
+func PlatformNames() []string {
+	files, _ := fs.Glob(examples.FS, "*.yaml")
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = builtinName(file)
+	}
+	return names
+}
+
+// Platforms describes every built in validator, in order of name
+func Platforms() ([]Platform, error) {
+	var platforms []Platform
+	for _, name := range PlatformNames() {
+		v, err := LoadValidator(BuiltinLocation(name), LoadOptions{})
+		if err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, Platform{Name: name, Description: v.Description, Documentation: v.Documentation})
+	}
+	return platforms, nil
+}
+
+func readBuiltin(location string) ([]byte, error) {
+	name := builtinName(location)
+	b, err := fs.ReadFile(examples.FS, name+".yaml")
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		return nil, exitcode.Errorf(exitcode.ConfigError, "unknown platform '%s', must be one of %s%s",
+			name, strings.Join(PlatformNames(), ", "), suggestName(name, PlatformNames()))
+	}
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.RuntimeError, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,66 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatforms(t *testing.T) {
+	assert := assert.New(t)
+
+	// Every example is built in
+	paths, err := filepath.Glob("../../examples/*.yaml")
+	assert.Nil(err)
+	platforms, err := Platforms()
+	assert.Nil(err)
+	assert.Len(platforms, len(paths))
+	assert.Contains(PlatformNames(), "dask-scheduler")
+	assert.Contains(platforms, Platform{
+		Name:          "kubeflow",
+		Description:   "Kubeflow notebooks",
+		Documentation: "https://www.kubeflow.org/docs/components/notebooks/container-images/#custom-images",
+	})
+
+	validator, err := LoadValidator("builtin:kubeflow", LoadOptions{Parameters: map[string]string{"user": "alice"}})
+	if assert.Nil(err) {
+		assert.Equal("👩 User is alice", validator.Checks[0].Description)
+	}
+
+	_, err = LoadValidator("builtin:kubeflwo", LoadOptions{})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "unknown platform 'kubeflwo', must be one of awesome, binder, ")
+	assert.Contains(err.Error(), "did you mean 'kubeflow'?")
+
+	_, err = LoadValidator("builtin:../go.mod", LoadOptions{})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+}
+
+func TestIncludeBuiltin(t *testing.T) {
+	dir := writeManifests(t, map[string]string{
+		"platform.yaml": "include: [builtin:security]\n",
+	})
+	validator, err := LoadValidatorFromFile(filepath.Join(dir, "platform.yaml"))
+	if assert.Nil(t, err) {
+		assert.NotEmpty(t, validator.Checks)
+	}
+}
@@ -0,0 +1,278 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+)
+
+// loader loads a manifest along with the validators it extends and includes
+type loader struct {
+	// The manifests currently being loaded, outermost first, to detect cycles
+	stack []string
+	// Every parameter declared by the manifests that were loaded
+	declared map[string]bool
+	// The key manifests from URLs must be signed with, if any
+	publicKey *minisignPublicKey
+}
+
+func newLoader() *loader {
+	return &loader{declared: map[string]bool{}}
+}
+
+// checkValues reports values for parameters that no manifest declares, which
+// are most likely misspelt
+func (l *loader) checkValues(values map[string]string) error {
+	var declared, unknown []string
+	for name := range l.declared {
+		declared = append(declared, name)
+	}
+	for name := range values {
+		if !l.declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return exitcode.Errorf(exitcode.ConfigError, "unknown parameter '%s'%s", unknown[0], suggestName(unknown[0], declared))
+}
+
+// manifestKey identifies a manifest however it is referred to
+func manifestKey(location string) string {
+	if isBuiltin(location) {
+		return BuiltinLocation(builtinName(location))
+	}
+	if !isURL(location) {
+		if abs, err := filepath.Abs(location); err == nil {
+			return abs
+		}
+	}
+	return location
+}
+
+func (l *loader) load(location string, values map[string]string) (*canaryv1.Validator, error) {
+	key := manifestKey(location)
+	if i := slices.Index(l.stack, key); i >= 0 {
+		cycle := append(slices.Clone(l.stack[i:]), key)
+		for j := range cycle {
+			cycle[j] = l.display(cycle[j])
+		}
+		return nil, exitcode.Errorf(exitcode.ConfigError, "cycle of validators extending or including each other: %s", strings.Join(cycle, " -> "))
+	}
+	l.stack = append(l.stack, key)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	b, err := l.read(location)
+	if err != nil {
+		return nil, err
+	}
+	return l.loadBytes(b, location, values)
+}
+
+// loadBytes strictly decodes a manifest, reporting every problem with its
+// position in source, and then composes it with its parent and includes
+func (l *loader) loadBytes(b []byte, source string, values map[string]string) (*canaryv1.Validator, error) {
+	validator, nodes, errs := decodeValidator(b, values)
+	if len(errs) > 0 {
+		return nil, exitcode.Wrap(exitcode.ConfigError, errs.withSource(source))
+	}
+	return l.compose(validator, nodes, source, values)
+}
+
+// display shortens absolute paths in the working directory for messages
+func (l *loader) display(key string) string {
+	if wd, err := filepath.Abs("."); err == nil && !isURL(key) && !isBuiltin(key) {
+		if rel, err := filepath.Rel(wd, key); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return key
+}
+
+// compose builds the validator a manifest describes. The parent it extends
+// comes first, then the checks, env, ports and volumes of each include in
+// order, and finally the manifest itself. Each layer overrides the ones
+// before it: metadata and dockerRunOptions that are set replace what was
+// inherited, and list entries with the same key (the check name, env var
+// name, port number or volume mount path) replace the earlier entry in place.
+// Docker options can't be merged like that as they don't all have a name, and
+// some may be given more than once. Checks must be named so they can be
+// overridden.
+//
+// Parameters work the other way around, the values a manifest ends up with
+// are passed on to its parent and includes so that it can change their defaults.
+func (l *loader) compose(v *canaryv1.Validator, nodes map[string]*yaml.Node, source string, values map[string]string) (*canaryv1.Validator, error) {
+	for name := range v.Parameters {
+		l.declared[name] = true
+	}
+	if v.Extends == "" && len(v.Include) == 0 {
+		return v, nil
+	}
+
+	parameters := effectiveParameters(v.Parameters, values)
+	composed := &canaryv1.Validator{}
+	if v.Extends != "" {
+		parent, err := l.loadReference(v.Extends, source, nodes["extends"], parameters)
+		if err != nil {
+			return nil, err
+		}
+		composed = parent
+	}
+	for i, include := range v.Include {
+		included, err := l.loadReference(include, source, nodes[fmt.Sprintf("include[%d]", i)], parameters)
+		if err != nil {
+			return nil, err
+		}
+		mergeLists(composed, included)
+	}
+	mergeValidator(composed, v)
+	return composed, nil
+}
+
+// loadReference loads a manifest named in another, reporting problems finding
+// it at the reference rather than leaving the user to work out where it came from
+func (l *loader) loadReference(reference string, source string, node *yaml.Node, values map[string]string) (*canaryv1.Validator, error) {
+	v, err := l.loadResolved(reference, source, values)
+	var manifestErrs ManifestErrors
+	if err == nil || errors.As(err, &manifestErrs) {
+		return v, err
+	}
+	referenceErr := ManifestError{Source: source, Message: err.Error()}
+	if node != nil {
+		referenceErr.Line, referenceErr.Column = node.Line, node.Column
+	}
+	return nil, exitcode.Wrap(exitcode.Of(err), ManifestErrors{referenceErr})
+}
+
+func (l *loader) loadResolved(reference string, source string, values map[string]string) (*canaryv1.Validator, error) {
+	// A pin or signature only covers the manifest it is on, so a remote manifest
+	// must not be able to pull in files from the machine it is loaded on
+	if (isURL(source) || isOCI(source)) && !isURL(reference) && !isBuiltin(reference) && filepath.IsAbs(reference) {
+		return nil, exitcode.Errorf(exitcode.ConfigError, "remote manifests can't refer to local file '%s'", reference)
+	}
+	v, err := l.load(resolveReference(source, reference), values)
+	if err != nil {
+		return nil, err
+	}
+	for i, check := range v.Checks {
+		if check.Name == "" {
+			return nil, exitcode.Errorf(exitcode.ConfigError, "check %d of '%s' has no name, checks must be named to be extended or included", i+1, reference)
+		}
+	}
+	return v, nil
+}
+
+// resolveReference resolves a path or URL relative to the manifest it is in
+func resolveReference(source string, reference string) string {
+	if isURL(reference) || isBuiltin(reference) || filepath.IsAbs(reference) {
+		return reference
+	}
+	if isBuiltin(source) {
+		return BuiltinLocation(path.Join(path.Dir(builtinName(source)), reference))
+	}
+	if isOCI(source) {
+		return resolveOCIReference(source, reference)
+	}
+	if isURL(source) {
+		base, err := url.Parse(source)
+		if err != nil {
+			return reference
+		}
+		ref, err := url.Parse(reference)
+		if err != nil {
+			return reference
+		}
+		return base.ResolveReference(ref).String()
+	}
+	return filepath.Join(filepath.Dir(source), reference)
+}
+
+// mergeValidator overrides base with everything that is set in overlay
+func mergeValidator(base *canaryv1.Validator, overlay *canaryv1.Validator) {
+	base.APIVersion = overlay.APIVersion
+	base.Kind = overlay.Kind
+	base.Extends = ""
+	base.Include = nil
+	if overlay.Name != "" {
+		base.Name = overlay.Name
+	}
+	if overlay.Description != "" {
+		base.Description = overlay.Description
+	}
+	if overlay.Documentation != "" {
+		base.Documentation = overlay.Documentation
+	}
+	if overlay.Command != nil {
+		base.Command = overlay.Command
+	}
+	if overlay.SecurityContext != nil {
+		base.SecurityContext = overlay.SecurityContext
+	}
+	if overlay.DockerRunOptions != nil {
+		base.DockerRunOptions = overlay.DockerRunOptions
+	}
+	base.Parameters = effectiveParameters(base.Parameters, overlay.Parameters)
+	mergeLists(base, overlay)
+}
+
+// mergeLists adds the checks, env, ports and volumes of overlay to base
+func mergeLists(base *canaryv1.Validator, overlay *canaryv1.Validator) {
+	base.Checks = mergeByKey(base.Checks, overlay.Checks, func(c canaryv1.Check) string { return c.Name })
+	base.Env = mergeByKey(base.Env, overlay.Env, func(e v1.EnvVar) string { return e.Name })
+	base.Ports = mergeByKey(base.Ports, overlay.Ports, func(p v1.ServicePort) string { return strconv.Itoa(int(p.Port)) })
+	base.Volumes = mergeByKey(base.Volumes, overlay.Volumes, func(v canaryv1.Volume) string { return v.MountPath })
+}
+
+// mergeByKey replaces entries of base with the entry in overlay that has the
+// same key, and appends the rest. Entries without a key are always appended.
+func mergeByKey[T any](base []T, overlay []T, key func(T) string) []T {
+	merged := slices.Clone(base)
+	index := map[string]int{}
+	for i, item := range merged {
+		if k := key(item); k != "" {
+			index[k] = i
+		}
+	}
+	for _, item := range overlay {
+		k := key(item)
+		if i, ok := index[k]; ok && k != "" {
+			merged[i] = item
+			continue
+		}
+		if k != "" {
+			index[k] = len(merged)
+		}
+		merged = append(merged, item)
+	}
+	return merged
+}
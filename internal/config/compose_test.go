@@ -0,0 +1,241 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeManifests(t *testing.T, manifests map[string]string) string {
+	dir := t.TempDir()
+	for name, manifest := range manifests {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("apiVersion: container-canary.nvidia.com/v1\nkind: Validator\n"+manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func checkNames(t *testing.T, path string) []string {
+	validator, err := LoadValidatorFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, check := range validator.Checks {
+		names = append(names, check.Name)
+	}
+	return names
+}
+
+func TestExtendsAndInclude(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := writeManifests(t, map[string]string{
+		"base.yaml": `
+name: base
+description: Base NVIDIA image
+dockerRunOptions: ["--gpus=all"]
+env:
+  - name: MODE
+    value: base
+ports:
+  - port: 8888
+checks:
+  - name: user
+    description: User is root
+    probe:
+      exec:
+        command: ["true"]
+  - name: http
+    description: Serves HTTP
+    probe:
+      httpGet:
+        port: 8888
+`,
+		"fragments/python.yaml": `
+name: python
+env:
+  - name: PYTHONUNBUFFERED
+    value: "1"
+checks:
+  - name: python
+    description: Has Python
+    probe:
+      exec:
+        command: ["python", "--version"]
+`,
+		"platforms/kubeflow.yaml": `
+extends: ../base.yaml
+include:
+  - ../fragments/python.yaml
+name: kubeflow
+dockerRunOptions: ["--shm-size=1g"]
+env:
+  - name: MODE
+    value: kubeflow
+checks:
+  - name: user
+    description: User is jovyan
+    probe:
+      exec:
+        command: ["true"]
+  - name: home
+    description: Home is /home/jovyan
+    probe:
+      exec:
+        command: ["true"]
+`,
+	})
+
+	validator, err := LoadValidatorFromFile(filepath.Join(dir, "platforms/kubeflow.yaml"))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal("kubeflow", validator.Name)
+	assert.Equal("Base NVIDIA image", validator.Description)
+	assert.Empty(validator.Extends)
+	assert.Empty(validator.Include)
+	// Docker options replace the inherited ones rather than passing both
+	assert.Equal([]string{"--shm-size=1g"}, validator.DockerRunOptions)
+
+	// Overridden checks keep their place, new ones come after
+	var descriptions []string
+	for _, check := range validator.Checks {
+		descriptions = append(descriptions, check.Description)
+	}
+	assert.Equal([]string{"User is jovyan", "Serves HTTP", "Has Python", "Home is /home/jovyan"}, descriptions)
+
+	assert.Len(validator.Env, 2)
+	assert.Equal("kubeflow", validator.Env[0].Value)
+	assert.Equal("PYTHONUNBUFFERED", validator.Env[1].Name)
+	assert.Len(validator.Ports, 1)
+}
+
+func TestExtendsChain(t *testing.T) {
+	dir := writeManifests(t, map[string]string{
+		"a.yaml": "checks:\n  - name: a\n    probe:\n      exec:\n        command: [\"true\"]\n",
+		"b.yaml": "extends: a.yaml\nchecks:\n  - name: b\n    probe:\n      exec:\n        command: [\"true\"]\n",
+		"c.yaml": "extends: b.yaml\ninclude: [a.yaml]\n",
+	})
+	assert.Equal(t, []string{"a", "b"}, checkNames(t, filepath.Join(dir, "c.yaml")))
+}
+
+func TestCompositionErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := writeManifests(t, map[string]string{
+		"a.yaml":       "extends: b.yaml\n",
+		"b.yaml":       "include:\n  - a.yaml\n",
+		"missing.yaml": "include:\n  - nothere.yaml\n",
+		"broken.yaml":  "extends: typo.yaml\n",
+		"typo.yaml":    "descripton: typo\n",
+	})
+
+	_, err := LoadValidatorFromFile(filepath.Join(dir, "a.yaml"))
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "b.yaml:4:5: cycle of validators extending or including each other: ")
+	assert.Contains(err.Error(), "a.yaml -> ")
+
+	_, err = LoadValidatorFromFile(filepath.Join(dir, "missing.yaml"))
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "missing.yaml:4:5: no such file ")
+
+	// Problems in a parent are reported in the parent
+	_, err = LoadValidatorFromFile(filepath.Join(dir, "broken.yaml"))
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Equal(filepath.Join(dir, "typo.yaml")+":3:1: unknown field 'descripton' in the manifest, did you mean 'description'?", err.Error())
+}
+
+func TestComposedChecksMustBeNamed(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := writeManifests(t, map[string]string{
+		"base.yaml":    "checks:\n  - probe:\n      exec:\n        command: [\"true\"]\n",
+		"child.yaml":   "extends: base.yaml\n",
+		"unnamed.yaml": "include: [child.yaml]\nchecks:\n  - probe:\n      exec:\n        command: [\"true\"]\n",
+	})
+
+	// A standalone manifest doesn't need to name its checks
+	_, err := LoadValidatorFromFile(filepath.Join(dir, "base.yaml"))
+	assert.Nil(err)
+
+	_, err = LoadValidatorFromFile(filepath.Join(dir, "child.yaml"))
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "child.yaml:3:10: check 1 of 'base.yaml' has no name")
+
+	_, err = LoadValidatorFromFile(filepath.Join(dir, "unnamed.yaml"))
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "unnamed.yaml:5:5: check has no name")
+}
+
+func TestExtendsURL(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := writeManifests(t, map[string]string{
+		"validators/base.yaml":  "name: base\nchecks:\n  - name: a\n    probe:\n      exec:\n        command: [\"true\"]\n",
+		"validators/child.yaml": "extends: base.yaml\nname: child\n",
+	})
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	validator, err := LoadValidatorFromURL(server.URL + "/validators/child.yaml")
+	if assert.Nil(err) {
+		assert.Equal("child", validator.Name)
+		assert.Len(validator.Checks, 1)
+	}
+}
+
+func TestRemoteManifestLocalReference(t *testing.T) {
+	assert := assert.New(t)
+
+	local := writeManifests(t, map[string]string{
+		"local.yaml": "name: local\n",
+	})
+	dir := writeManifests(t, map[string]string{
+		"remote.yaml": "extends: " + filepath.Join(local, "local.yaml") + "\n",
+	})
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	_, err := LoadValidatorFromURL(server.URL + "/remote.yaml")
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "remote manifests can't refer to local file")
+}
+
+func TestResolveReference(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("examples/base.yaml", resolveReference("examples/kubeflow.yaml", "base.yaml"))
+	assert.Equal("base.yaml", resolveReference("", "base.yaml"))
+	assert.Equal("/etc/canary/base.yaml", resolveReference("examples/kubeflow.yaml", "/etc/canary/base.yaml"))
+	assert.Equal("https://example.com/validators/base.yaml", resolveReference("https://example.com/validators/kubeflow.yaml", "base.yaml"))
+	assert.Equal("https://example.com/base.yaml", resolveReference("https://example.com/validators/kubeflow.yaml", "../base.yaml"))
+	assert.Equal("https://example.org/base.yaml", resolveReference("examples/kubeflow.yaml", "https://example.org/base.yaml"))
+}
@@ -0,0 +1,105 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestError is a problem at a position in a manifest
+type ManifestError struct {
+	// The file or URL of the manifest, if known
+	Source string
+	Line   int
+	// Zero if the column isn't known
+	Column  int
+	Message string
+}
+
+func (e ManifestError) Error() string {
+	return e.position() + e.Message
+}
+
+// position formats the location of the error as 'source:line:column: '
+func (e ManifestError) position() string {
+	var b strings.Builder
+	if e.Source != "" {
+		b.WriteString(e.Source)
+		b.WriteString(":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&b, "%d:", e.Column)
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	return b.String()
+}
+
+// ManifestErrors are all the problems found in a manifest, in the order they appear
+type ManifestErrors []ManifestError
+
+func (e ManifestErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// withSource sets the source of every error
+func (e ManifestErrors) withSource(source string) ManifestErrors {
+	for i := range e {
+		e[i].Source = source
+	}
+	return e
+}
+
+func errorAt(node *yaml.Node, format string, a ...interface{}) ManifestError {
+	return ManifestError{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, a...)}
+}
+
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlErrors converts the errors of the YAML library, which only know their line
+func yamlErrors(err error) ManifestErrors {
+	var messages []string
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+	var errs ManifestErrors
+	for _, message := range messages {
+		if m := yamlLinePattern.FindStringSubmatch(message); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			errs = append(errs, ManifestError{Line: line, Message: m[2]})
+		} else {
+			errs = append(errs, ManifestError{Message: strings.TrimPrefix(message, "yaml: ")})
+		}
+	}
+	return errs
+}
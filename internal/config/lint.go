@@ -0,0 +1,164 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/exitcode"
+	v1 "k8s.io/api/core/v1"
+)
+
+// FindingLevel is how serious a lint finding is. It is unrelated to the
+// severity of a check, which is how much the check failing matters.
+type FindingLevel string
+
+const (
+	// The manifest can't be used
+	LevelError FindingLevel = "error"
+	// The manifest loads but is probably not what the author intended
+	LevelWarning FindingLevel = "warning"
+)
+
+// Finding is a problem found by linting a manifest
+type Finding struct {
+	ManifestError
+	Level FindingLevel
+}
+
+// String formats the finding as a compiler would, 'file:line:column: level: message'
+func (f Finding) String() string {
+	return fmt.Sprintf("%s%s: %s", f.position(), f.Level, f.Message)
+}
+
+// LintFile lints the manifest at path. An error is only returned if the file
+// can't be read, problems with its contents are findings.
+func LintFile(path string) ([]Finding, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.ConfigError, err)
+	}
+	return Lint(b, path), nil
+}
+
+// Lint reports everything that would stop a manifest from loading as errors,
+// and likely mistakes in a manifest which does load as warnings
+func Lint(b []byte, source string) []Finding {
+	validator, nodes, errs := decodeValidator(b, nil)
+	var findings []Finding
+	for _, err := range errs {
+		findings = append(findings, Finding{ManifestError: err, Level: LevelError})
+	}
+	if validator != nil {
+		ports := validator.Ports
+		if validator.Extends != "" || len(validator.Include) > 0 {
+			loader := newLoader()
+			loader.stack = append(loader.stack, manifestKey(source))
+			composed, err := loader.compose(validator, nodes, source, nil)
+			if err != nil {
+				findings = append(findings, errorFindings(err)...)
+			} else {
+				ports = composed.Ports
+			}
+		}
+		l := &manifestValidator{nodes: nodes}
+		lintValidator(l, validator, ports)
+		for _, err := range l.errs {
+			findings = append(findings, Finding{ManifestError: err, Level: LevelWarning})
+		}
+	}
+
+	for i := range findings {
+		if findings[i].Source == "" {
+			findings[i].Source = source
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings
+}
+
+// errorFindings turns an error loading the validators a manifest extends or
+// includes into findings
+func errorFindings(err error) []Finding {
+	var errs ManifestErrors
+	if !errors.As(err, &errs) {
+		errs = ManifestErrors{{Message: err.Error()}}
+	}
+	findings := make([]Finding, len(errs))
+	for i, e := range errs {
+		findings[i] = Finding{ManifestError: e, Level: LevelError}
+	}
+	return findings
+}
+
+// lintValidator warns about likely mistakes, published are the ports of the
+// validator once composed with anything it extends or includes
+func lintValidator(l *manifestValidator, v *canaryv1.Validator, published []v1.ServicePort) {
+	ports := map[int]bool{}
+	for _, port := range published {
+		ports[int(port.Port)] = true
+	}
+
+	for i, check := range v.Checks {
+		p := fmt.Sprintf("checks[%d]", i)
+		if check.Description == "" {
+			l.errorf(p, "check '%s' has no description, it is shown in results instead of the name", check.Name)
+		}
+
+		probe := check.Probe
+		var port int
+		var portPath string
+		if probe.HTTPGet != nil {
+			port, portPath = probe.HTTPGet.Port, p+".probe.httpGet.port"
+		} else if probe.TCPSocket != nil {
+			port, portPath = probe.TCPSocket.Port, p+".probe.tcpSocket.port"
+		}
+		if port > 0 && !ports[port] {
+			l.errorf(portPath, "check '%s' probes port %d which is not listed in ports, so it won't be published", check.Name, port)
+		}
+
+		if probe.Exec != nil && len(probe.Exec.Command) > 0 && usesBash(probe.Exec.Command) && !isAboutBash(check) {
+			l.errorf(p+".probe.exec.command[0]",
+				"check '%s' runs its command with bash, which many images don't have; use /bin/sh unless the check is about bash", check.Name)
+		}
+	}
+}
+
+// usesBash reports whether a command runs bash, directly or through env
+func usesBash(command []string) bool {
+	program := path.Base(command[0])
+	if program == "env" && len(command) > 1 {
+		program = path.Base(command[1])
+	}
+	return program == "bash"
+}
+
+func isAboutBash(check canaryv1.Check) bool {
+	return strings.Contains(strings.ToLower(check.Name+" "+check.Description), "bash")
+}
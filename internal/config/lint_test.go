@@ -0,0 +1,109 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	assert := assert.New(t)
+
+	findings := Lint([]byte(`apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+name: mistakes
+ports:
+  - port: 8888
+checks:
+  - name: http
+    description: Serves HTTP
+    probe:
+      httpGet:
+        port: 8080
+  - name: java
+    probe:
+      exec:
+        command: ["/bin/bash", "-c", "java -version"]
+  - name: bash
+    description: Has bash installed
+    probe:
+      exec:
+        command: ["/usr/bin/env", "bash", "--version"]
+  - name: java
+    description: Has Java
+    probe:
+      exec:
+        command: ["java", "-version"]
+`), "mistakes.yaml")
+
+	var lines []string
+	for _, finding := range findings {
+		lines = append(lines, finding.String())
+	}
+	assert.Equal([]string{
+		"mistakes.yaml:11:15: warning: check 'http' probes port 8080 which is not listed in ports, so it won't be published",
+		"mistakes.yaml:12:5: warning: check 'java' has no description, it is shown in results instead of the name",
+		"mistakes.yaml:15:19: warning: check 'java' runs its command with bash, which many images don't have; use /bin/sh unless the check is about bash",
+		"mistakes.yaml:21:11: error: duplicate check name 'java', first used on line 12",
+	}, lines)
+}
+
+func TestLintSchemaErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	findings := Lint([]byte("apiVersion: container-canary.nvidia.com/v1\nkind: Validator\nchecks:\n  - descripton: typo\n"), "typo.yaml")
+	if assert.Len(findings, 1) {
+		assert.Equal(LevelError, findings[0].Level)
+		assert.Equal("typo.yaml:4:5: error: unknown field 'descripton' in checks[0], did you mean 'description'?", findings[0].String())
+	}
+}
+
+func TestLintExamples(t *testing.T) {
+	paths, err := filepath.Glob("../../examples/*.yaml")
+	assert.Nil(t, err)
+	for _, path := range paths {
+		findings, err := LintFile(path)
+		assert.Nil(t, err)
+		assert.Empty(t, findings, path)
+	}
+}
+
+func TestLintComposed(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := writeManifests(t, map[string]string{
+		"base.yaml":  "ports:\n  - port: 8888\n",
+		"child.yaml": "extends: base.yaml\nchecks:\n  - name: http\n    description: Serves HTTP\n    probe:\n      httpGet:\n        port: 8888\n",
+		"cycle.yaml": "include: [cycle.yaml]\n",
+	})
+
+	// Ports published by the parent count
+	findings, err := LintFile(filepath.Join(dir, "child.yaml"))
+	assert.Nil(err)
+	assert.Empty(findings)
+
+	findings, err = LintFile(filepath.Join(dir, "cycle.yaml"))
+	assert.Nil(err)
+	if assert.Len(findings, 1) {
+		assert.Equal(LevelError, findings[0].Level)
+		assert.Contains(findings[0].Message, "cycle of validators")
+	}
+}
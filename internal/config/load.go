@@ -19,57 +19,129 @@ package config
 
 import (
 	"errors"
-	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 
 	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
-	yaml "gopkg.in/yaml.v2"
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"gopkg.in/yaml.v3"
 )
 
-func LoadValidatorFromURL(url string) (*canaryv1.Validator, error) {
-	resp, err := http.Get(url)
+// LoadOptions change how manifests are loaded
+type LoadOptions struct {
+	// Values for the parameters of the validator, overriding their defaults
+	Parameters map[string]string
+	// Path of a minisign public key. If set every manifest loaded from a URL
+	// or registry must have a detached signature made with the matching key.
+	PublicKey string
+}
+
+// LoadValidator loads a manifest from a file, URL or registry, along with everything it
+// extends or includes
+func LoadValidator(location string, options LoadOptions) (*canaryv1.Validator, error) {
+	l := newLoader()
+	if options.PublicKey != "" {
+		b, err := os.ReadFile(options.PublicKey)
+		if err != nil {
+			return nil, exitcode.Wrap(exitcode.ConfigError, err)
+		}
+		if l.publicKey, err = parseMinisignPublicKey(b); err != nil {
+			return nil, exitcode.Errorf(exitcode.ConfigError, "%s: %s", options.PublicKey, err.Error())
+		}
+	}
+	v, err := l.load(location, options.Parameters)
 	if err != nil {
 		return nil, err
 	}
-
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	if err := l.checkValues(options.Parameters); err != nil {
 		return nil, err
 	}
+	return v, nil
+}
 
-	return LoadValidatorFromBytes(body)
+func LoadValidatorFromURL(url string) (*canaryv1.Validator, error) {
+	return LoadValidator(url, LoadOptions{})
 }
 
 func LoadValidatorFromFile(path string) (*canaryv1.Validator, error) {
+	return LoadValidator(path, LoadOptions{})
+}
+
+// LoadValidatorFromBytes loads a manifest, resolving anything it extends or
+// includes relative to the working directory
+func LoadValidatorFromBytes(b []byte) (*canaryv1.Validator, error) {
+	return newLoader().loadBytes(b, "", nil)
+}
+
+// read reads a manifest from a URL, registry, file or the built in validators
+func (l *loader) read(location string) ([]byte, error) {
+	if isBuiltin(location) {
+		return readBuiltin(location)
+	}
+	if isOCI(location) {
+		return l.pull(location)
+	}
+	if isURL(location) {
+		return l.fetch(location)
+	}
+	return readManifestFile(location)
+}
+
+func readManifestFile(path string) ([]byte, error) {
 	filename, err := filepath.Abs(path)
 	if err != nil {
-		return nil, err
+		return nil, exitcode.Wrap(exitcode.ConfigError, err)
 	}
 
 	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
-		return nil, fmt.Errorf("no such file %s", filename)
+		return nil, exitcode.Errorf(exitcode.ConfigError, "no such file %s", filename)
 	}
 
 	yamlFile, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, err
+		return nil, exitcode.Wrap(exitcode.ConfigError, err)
 	}
+	return yamlFile, nil
+}
 
-	return LoadValidatorFromBytes(yamlFile)
+func isURL(location string) bool {
+	return strings.Contains(location, "://")
 }
 
-func LoadValidatorFromBytes(b []byte) (*canaryv1.Validator, error) {
-	var validator canaryv1.Validator
+// decodeValidator returns the nodes of the document by path along with the
+// validator, so that later problems can be reported at their position. The
+// validator is nil if the document could not be decoded. References to
+// parameters are replaced before decoding, using values in preference to the
+// defaults the manifest declares.
+func decodeValidator(b []byte, values map[string]string) (*canaryv1.Validator, map[string]*yaml.Node, ManifestErrors) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(b, &document); err != nil {
+		return nil, nil, yamlErrors(err)
+	}
+	if len(document.Content) == 0 {
+		return nil, nil, ManifestErrors{{Message: "manifest is empty"}}
+	}
+	root := document.Content[0]
 
-	err := yaml.Unmarshal(b, &validator)
-	if err != nil {
-		return nil, err
+	decoder := newStrictDecoder()
+	declared := decodeParameters(root, decoder)
+	if len(decoder.errs) > 0 {
+		return nil, nil, decoder.errs
+	}
+	if errs := substituteParameters(root, effectiveParameters(declared, values)); len(errs) > 0 {
+		return nil, nil, errs
 	}
 
-	return &validator, nil
+	decoder.check(root, reflect.TypeOf(canaryv1.Validator{}), "")
+	if len(decoder.errs) > 0 {
+		return nil, nil, decoder.errs
+	}
+
+	var validator canaryv1.Validator
+	if err := root.Decode(&validator); err != nil {
+		return nil, nil, yamlErrors(err)
+	}
+	return &validator, decoder.nodes, validateManifest(&validator, decoder.nodes)
 }
@@ -18,8 +18,12 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/nvidia/container-canary/internal/exitcode"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -57,3 +61,186 @@ func TestValidator(t *testing.T) {
 	assert.Equal("Access-Control-Allow-Origin", header.Name)
 	assert.Equal("*", header.Value)
 }
+
+func TestImageSizeProbe(t *testing.T) {
+	assert := assert.New(t)
+
+	validator, err := LoadValidatorFromBytes([]byte(`
+apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+name: size
+checks:
+  - name: image-size
+    probe:
+      imageSize:
+        maxSize: 2GiB
+        maxCompressedSize: 500MB
+        maxLayers: 127
+        maxLayerSize: 1048576
+`))
+
+	assert.Nil(err)
+	action := validator.Checks[0].Probe.ImageSize
+	assert.NotNil(action)
+	assert.EqualValues(2*1024*1024*1024, action.MaxSize)
+	assert.EqualValues(500*1000*1000, action.MaxCompressedSize)
+	assert.Equal(127, action.MaxLayers)
+	assert.EqualValues(1048576, action.MaxLayerSize)
+
+	_, err = LoadValidatorFromBytes([]byte(`
+apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+checks:
+  - probe:
+      imageSize:
+        maxSize: big
+`))
+	assert.NotNil(err)
+}
+
+func TestSecurityContext(t *testing.T) {
+	assert := assert.New(t)
+
+	validator, err := LoadValidatorFromBytes([]byte(`
+apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+name: read-only
+securityContext:
+  readOnlyRootFilesystem: true
+volumes:
+  - mountPath: /tmp
+    tmpfs: true
+checks:
+  - name: writes
+    probe:
+      readOnlyRootFilesystem:
+        allow:
+          - /var/log/
+`))
+
+	assert.Nil(err)
+	assert.True(validator.SecurityContext.ReadOnlyRootFilesystem)
+	assert.True(validator.Volumes[0].Tmpfs)
+	assert.Equal([]string{"/var/log/"}, validator.Checks[0].Probe.ReadOnlyRootFilesystem.Allow)
+}
+
+func TestExamples(t *testing.T) {
+	paths, err := filepath.Glob("../../examples/*.yaml")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, paths)
+	for _, path := range paths {
+		_, err := LoadValidatorFromFile(path)
+		assert.Nil(t, err, path)
+	}
+}
+
+func TestStrictFields(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := LoadValidatorFromBytes([]byte(`apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+name: typos
+checks:
+  - name: http
+    probe:
+      httpget:
+        port: 8888
+      failureTreshold: 3
+  - name: flag
+    probe:
+      exec:
+        command: ["true"]
+      periodSeconds: often
+`))
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	var errs ManifestErrors
+	if assert.ErrorAs(err, &errs) {
+		assert.Equal(ManifestErrors{
+			{Line: 7, Column: 7, Message: "unknown field 'httpget' in checks[0].probe, did you mean 'httpGet'?"},
+			{Line: 9, Column: 7, Message: "unknown field 'failureTreshold' in checks[0].probe, did you mean 'failureThreshold'?"},
+			{Line: 14, Column: 22, Message: "checks[1].probe.periodSeconds must be an integer, not 'often'"},
+		}, errs)
+	}
+}
+
+func TestSemanticValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := LoadValidatorFromBytes([]byte(`apiVersion: container-canary.nvidia.com/v2
+kind: Validator
+name: nonsense
+ports:
+  - port: 70000
+checks:
+  - name: http
+    probe:
+      httpGet:
+        port: 0
+      successThreshold: 0
+      timeoutSeconds: -1
+  - name: http
+    probe:
+      tcpSocket:
+        port: 22
+      exec:
+        command: ["true"]
+  - name: rules
+    probe:
+      security:
+        rule: noRoot
+`))
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Equal(`1:13: unsupported apiVersion 'container-canary.nvidia.com/v2', expected 'container-canary.nvidia.com/v1'
+5:11: port 70000 is out of range, it must be between 1 and 65535
+10:15: port 0 is out of range, it must be between 1 and 65535
+11:25: successThreshold must be at least 1
+12:23: timeoutSeconds must not be negative
+13:11: duplicate check name 'http', first used on line 7
+15:7: check 'http' has 2 probes, only one is allowed
+22:15: unknown security rule 'noRoot', must be one of noSetuid, noWorldWritable, nonRootPid1, noSecrets, noSSHServer`, err.Error())
+}
+
+func TestManifestErrorSource(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "validator.yaml")
+	assert.Nil(os.WriteFile(path, []byte("name: [unclosed\n"), 0o644))
+	_, err := LoadValidatorFromFile(path)
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.True(strings.HasPrefix(err.Error(), path+":"), err.Error())
+
+	assert.Nil(os.WriteFile(path, []byte("name: no-version\nchecks: []\n"), 0o644))
+	_, err = LoadValidatorFromFile(path)
+	assert.EqualError(err, path+":1:1: missing apiVersion, expected 'container-canary.nvidia.com/v1'\n"+path+":1:1: missing kind, expected 'Validator'")
+}
+
+func TestCheckSeverity(t *testing.T) {
+	assert := assert.New(t)
+
+	validator, err := LoadValidatorFromBytes([]byte(`apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+name: severities
+checks:
+  - name: cors
+    severity: warning
+    probe:
+      exec:
+        command: ["true"]
+`))
+	if assert.Nil(err) {
+		assert.Equal("warning", validator.Checks[0].Severity)
+	}
+
+	_, err = LoadValidatorFromBytes([]byte(`apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+name: severities
+checks:
+  - name: cors
+    severity: fatal
+    probe:
+      exec:
+        command: ["true"]
+`))
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.EqualError(err, "6:15: unknown severity 'fatal', must be one of error, warning, info")
+}
@@ -0,0 +1,206 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/nvidia/container-canary/internal/exitcode"
+)
+
+// OCIPrefix marks a validator stored as an artifact in an OCI registry, as
+// oci://registry/repository:tag or oci://registry/repository@sha256:digest
+const OCIPrefix = "oci://"
+
+const (
+	// Media type of the layer holding the manifest
+	ValidatorMediaType types.MediaType = "application/vnd.nvidia.container-canary.validator.v1+yaml"
+	// Media type of the layer holding a minisign signature of the manifest
+	SignatureMediaType types.MediaType = "application/vnd.nvidia.container-canary.validator.signature.v1+minisign"
+	// Media type of the artifact's config, which identifies it as a validator
+	ValidatorConfigMediaType types.MediaType = "application/vnd.nvidia.container-canary.config.v1+json"
+)
+
+func isOCI(location string) bool {
+	return strings.HasPrefix(location, OCIPrefix)
+}
+
+// parseOCIReference requires a tag or digest, so that validators pulled from
+// a registry are always versioned
+func parseOCIReference(location string) (name.Reference, error) {
+	ref, err := name.ParseReference(strings.TrimPrefix(location, OCIPrefix), name.StrictValidation)
+	if err != nil {
+		return nil, exitcode.Errorf(exitcode.ConfigError, "invalid OCI reference '%s': %s", location, err.Error())
+	}
+	return ref, nil
+}
+
+// pull gets a manifest from a registry. Digest references are verified by the
+// registry client, and like manifests from URLs the last verified copy is used
+// when the registry can't be reached.
+func (l *loader) pull(location string) ([]byte, error) {
+	ref, err := parseOCIReference(location)
+	if err != nil {
+		return nil, err
+	}
+	return l.fetchVerified(location, "", func() ([]byte, []byte, error) {
+		return l.pullArtifact(location, ref)
+	})
+}
+
+// pullArtifact gets the manifest, and its signature if manifests must be signed
+func (l *loader) pullArtifact(location string, ref name.Reference) ([]byte, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	img, err := remote.Image(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return nil, nil, registryError(location, err)
+	}
+	body, err := readArtifactLayer(img, ValidatorMediaType)
+	if err != nil {
+		return nil, nil, registryError(location, err)
+	}
+	if body == nil {
+		return nil, nil, exitcode.Errorf(exitcode.ConfigError, "%s is not a validator, it has no layer of type %s", location, ValidatorMediaType)
+	}
+	if l.publicKey == nil {
+		return body, nil, nil
+	}
+	signature, err := readArtifactLayer(img, SignatureMediaType)
+	if err != nil {
+		return nil, nil, registryError(location, err)
+	}
+	if signature == nil {
+		return nil, nil, exitcode.Errorf(exitcode.ConfigError, "%s is not signed, push it with --signature", location)
+	}
+	return body, signature, nil
+}
+
+// readArtifactLayer returns the contents of the first layer with a media
+// type, or nil if there isn't one
+func readArtifactLayer(img v1.Image, mediaType types.MediaType) ([]byte, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != mediaType {
+			continue
+		}
+		blob, err := img.LayerByDigest(layer.Digest)
+		if err != nil {
+			return nil, err
+		}
+		r, err := blob.Compressed()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+	return nil, nil
+}
+
+// registryError treats a response from the registry like an HTTP status, so
+// that only failures to reach it fall back to the cache
+func registryError(location string, err error) error {
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) {
+		return exitcode.Wrap(exitcode.RuntimeError, &statusError{url: location, status: transportErr.Error()})
+	}
+	return exitcode.Errorf(exitcode.RuntimeError, "failed to fetch %s: %s", location, err.Error())
+}
+
+// resolveOCIReference resolves a reference in a manifest from a registry to
+// another repository alongside it, so 'base:1.0' in
+// oci://registry/validators/kubeflow:1.2 is oci://registry/validators/base:1.0
+func resolveOCIReference(source string, reference string) string {
+	ref, err := parseOCIReference(source)
+	if err != nil {
+		return reference
+	}
+	return OCIPrefix + path.Join(path.Dir(ref.Context().Name()), reference)
+}
+
+// PushValidator publishes the manifest at path to a registry as an OCI
+// artifact, along with the minisign signature at signaturePath if it is set.
+// It returns the digest reference of the artifact, which can be used to pin it.
+func PushValidator(path string, location string, signaturePath string) (string, error) {
+	if !isOCI(location) {
+		return "", exitcode.Errorf(exitcode.ConfigError, "invalid OCI reference '%s', expected %sREGISTRY/REPOSITORY:TAG", location, OCIPrefix)
+	}
+	ref, err := parseOCIReference(location)
+	if err != nil {
+		return "", err
+	}
+	b, err := readManifestFile(path)
+	if err != nil {
+		return "", err
+	}
+	if _, _, errs := decodeValidator(b, nil); len(errs) > 0 {
+		return "", exitcode.Wrap(exitcode.ConfigError, errs.withSource(path))
+	}
+
+	adds := []mutate.Addendum{{
+		Layer:       static.NewLayer(b, ValidatorMediaType),
+		Annotations: map[string]string{"org.opencontainers.image.title": filepath.Base(path)},
+	}}
+	if signaturePath != "" {
+		signature, err := os.ReadFile(signaturePath)
+		if err != nil {
+			return "", exitcode.Wrap(exitcode.ConfigError, err)
+		}
+		adds = append(adds, mutate.Addendum{
+			Layer:       static.NewLayer(signature, SignatureMediaType),
+			Annotations: map[string]string{"org.opencontainers.image.title": filepath.Base(signaturePath)},
+		})
+	}
+	img, err := mutate.Append(empty.Image, adds...)
+	if err != nil {
+		return "", exitcode.Wrap(exitcode.RuntimeError, err)
+	}
+	img = mutate.ConfigMediaType(mutate.MediaType(img, types.OCIManifestSchema1), ValidatorConfigMediaType)
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	if err := remote.Write(ref, img, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain)); err != nil {
+		return "", exitcode.Errorf(exitcode.RuntimeError, "failed to push %s: %s", location, err.Error())
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return "", exitcode.Wrap(exitcode.RuntimeError, err)
+	}
+	return fmt.Sprintf("%s%s@%s", OCIPrefix, ref.Context().Name(), digest), nil
+}
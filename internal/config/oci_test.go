@@ -0,0 +1,158 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRegistry starts an in-process registry, returning its host
+func newRegistry(t *testing.T) (*httptest.Server, string) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(server.Close)
+	return server, strings.TrimPrefix(server.URL, "http://")
+}
+
+func writeManifest(t *testing.T, name string, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPushAndPull(t *testing.T) {
+	assert := assert.New(t)
+	useTempCache(t)
+	_, host := newRegistry(t)
+	path := writeManifest(t, "remote.yaml", remoteManifest)
+
+	reference, err := PushValidator(path, "oci://"+host+"/validators/remote:1.0", "")
+	assert.Nil(err)
+	assert.Regexp(`^oci://`+host+`/validators/remote@sha256:[0-9a-f]{64}$`, reference)
+
+	for _, location := range []string{"oci://" + host + "/validators/remote:1.0", reference} {
+		validator, err := LoadValidator(location, LoadOptions{})
+		if assert.Nil(err, location) {
+			assert.Equal("remote", validator.Name)
+		}
+	}
+
+	_, err = LoadValidator("oci://"+host+"/validators/remote:2.0", LoadOptions{})
+	assert.Equal(exitcode.RuntimeError, exitcode.Of(err))
+	assert.Contains(err.Error(), "failed to fetch oci://"+host+"/validators/remote:2.0")
+
+	_, err = LoadValidator("oci://"+host+"/validators/remote", LoadOptions{})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "invalid OCI reference")
+}
+
+func TestPushInvalid(t *testing.T) {
+	assert := assert.New(t)
+	_, host := newRegistry(t)
+
+	path := writeManifest(t, "invalid.yaml", "name: no-version\n")
+	_, err := PushValidator(path, "oci://"+host+"/validators/invalid:1.0", "")
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), path+":1:1: missing apiVersion")
+
+	path = writeManifest(t, "remote.yaml", remoteManifest)
+	_, err = PushValidator(path, host+"/validators/remote:1.0", "")
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "expected oci://REGISTRY/REPOSITORY:TAG")
+}
+
+func TestPullImage(t *testing.T) {
+	assert := assert.New(t)
+	useTempCache(t)
+	_, host := newRegistry(t)
+	img, err := random.Image(64, 1)
+	assert.Nil(err)
+	ref, err := name.ParseReference(host + "/images/random:latest")
+	assert.Nil(err)
+	assert.Nil(remote.Write(ref, img))
+
+	_, err = LoadValidator("oci://"+host+"/images/random:latest", LoadOptions{})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "is not a validator")
+}
+
+func TestPullSigned(t *testing.T) {
+	assert := assert.New(t)
+	useTempCache(t)
+	_, host := newRegistry(t)
+	key, publicKey := newMinisignKey(t)
+	path := writeManifest(t, "remote.yaml", remoteManifest)
+	signature := writeManifest(t, "remote.yaml.minisig", key.sign(remoteManifest))
+
+	_, err := PushValidator(path, "oci://"+host+"/validators/signed:1.0", signature)
+	assert.Nil(err)
+	_, err = PushValidator(path, "oci://"+host+"/validators/unsigned:1.0", "")
+	assert.Nil(err)
+
+	validator, err := LoadValidator("oci://"+host+"/validators/signed:1.0", LoadOptions{PublicKey: publicKey})
+	if assert.Nil(err) {
+		assert.Equal("remote", validator.Name)
+	}
+
+	_, err = LoadValidator("oci://"+host+"/validators/unsigned:1.0", LoadOptions{PublicKey: publicKey})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "is not signed")
+}
+
+func TestPullComposed(t *testing.T) {
+	assert := assert.New(t)
+	useTempCache(t)
+	server, host := newRegistry(t)
+	base := writeManifest(t, "base.yaml", remoteManifest)
+	child := writeManifest(t, "child.yaml", `apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+extends: base:1.0
+name: child
+`)
+	_, err := PushValidator(base, "oci://"+host+"/validators/base:1.0", "")
+	assert.Nil(err)
+	_, err = PushValidator(child, "oci://"+host+"/validators/child:1.0", "")
+	assert.Nil(err)
+
+	validator, err := LoadValidator("oci://"+host+"/validators/child:1.0", LoadOptions{})
+	if assert.Nil(err) {
+		assert.Equal("child", validator.Name)
+		assert.Equal("shell", validator.Checks[0].Name)
+	}
+
+	// Offline runs use the last copy pulled
+	server.Close()
+	validator, err = LoadValidator("oci://"+host+"/validators/child:1.0", LoadOptions{})
+	if assert.Nil(err) {
+		assert.Equal("child", validator.Name)
+	}
+}
@@ -0,0 +1,165 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	parameterReference  = regexp.MustCompile(`\$\{\{\s*(.*?)\s*\}\}`)
+	parameterExpression = regexp.MustCompile(`^params\.([A-Za-z_][A-Za-z0-9_-]*)$`)
+)
+
+// ParameterValues reads parameter values from YAML files of names and values,
+// and then from name=value pairs, with later values overriding earlier ones
+func ParameterValues(files []string, set []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, exitcode.Wrap(exitcode.ConfigError, err)
+		}
+		var fileValues map[string]string
+		if err := yaml.Unmarshal(b, &fileValues); err != nil {
+			return nil, exitcode.Wrap(exitcode.ConfigError, yamlErrors(err).withSource(file))
+		}
+		maps.Copy(values, fileValues)
+	}
+	for _, pair := range set {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, exitcode.Errorf(exitcode.ConfigError, "invalid parameter '%s', expected name=value", pair)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// effectiveParameters overrides the defaults a manifest declares with values
+// given on the command line or by a manifest which extends or includes it
+func effectiveParameters(declared map[string]string, values map[string]string) map[string]string {
+	parameters := maps.Clone(declared)
+	if parameters == nil {
+		parameters = map[string]string{}
+	}
+	maps.Copy(parameters, values)
+	return parameters
+}
+
+// decodeParameters decodes the parameters section of a manifest, if it has one
+func decodeParameters(root *yaml.Node, decoder *strictDecoder) map[string]string {
+	node := mappingValue(root, "parameters")
+	if node == nil {
+		return nil
+	}
+	decoder.check(node, reflect.TypeOf(map[string]string{}), "parameters")
+	if len(decoder.errs) > 0 {
+		return nil
+	}
+	var declared map[string]string
+	if err := node.Decode(&declared); err != nil {
+		decoder.errs = append(decoder.errs, yamlErrors(err)...)
+	}
+	return declared
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// substituteParameters replaces references to parameters in every value of
+// the document except the parameters themselves
+func substituteParameters(root *yaml.Node, parameters map[string]string) ManifestErrors {
+	var errs ManifestErrors
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node == root && node.Content[i].Value == "parameters" {
+					continue
+				}
+				walk(node.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				walk(item)
+			}
+		case yaml.ScalarNode:
+			errs = append(errs, substituteScalar(node, parameters)...)
+		}
+	}
+	walk(root)
+	return errs
+}
+
+func substituteScalar(node *yaml.Node, parameters map[string]string) ManifestErrors {
+	matches := parameterReference.FindAllStringSubmatchIndex(node.Value, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	var errs ManifestErrors
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		b.WriteString(node.Value[last:match[0]])
+		last = match[1]
+		expression := node.Value[match[2]:match[3]]
+		name := parameterExpression.FindStringSubmatch(expression)
+		if name == nil {
+			errs = append(errs, errorAt(node, "unsupported expression '%s', parameters are referenced as ${{ params.name }}", expression))
+			continue
+		}
+		value, ok := parameters[name[1]]
+		if !ok {
+			errs = append(errs, errorAt(node, "undefined parameter '%s'%s", name[1], suggestName(name[1], slices.Collect(maps.Keys(parameters)))))
+			continue
+		}
+		b.WriteString(value)
+	}
+	b.WriteString(node.Value[last:])
+
+	// A plain value which is just a reference takes the type of the value it
+	// is replaced with, so that parameters can be used for ports and timeouts
+	whole := len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(node.Value)
+	quoted := node.Style&(yaml.TaggedStyle|yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) != 0
+	node.Value = b.String()
+	if whole && !quoted {
+		node.Tag = ""
+		node.Tag = node.ShortTag()
+	}
+	return errs
+}
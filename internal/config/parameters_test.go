@@ -0,0 +1,148 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/stretchr/testify/assert"
+)
+
+const parameterisedManifest = `apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+name: notebooks
+parameters:
+  user: jovyan
+  uid: 1000
+  port: 8888
+env:
+  - name: NB_PREFIX
+    value: /hub/${{ params.user }}/
+ports:
+  - port: ${{ params.port }}
+checks:
+  - name: user
+    description: User is ${{ params.user }}
+    probe:
+      exec:
+        command: ["/bin/sh", "-c", "[ $(id -u) = ${{params.uid}} ]"]
+  - name: http
+    description: Serves HTTP
+    probe:
+      httpGet:
+        path: /hub/${{ params.user }}/
+        port: ${{ params.port }}
+`
+
+func TestParameters(t *testing.T) {
+	assert := assert.New(t)
+
+	validator, err := LoadValidatorFromBytes([]byte(parameterisedManifest))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal("/hub/jovyan/", validator.Env[0].Value)
+	assert.Equal(int32(8888), validator.Ports[0].Port)
+	assert.Equal("User is jovyan", validator.Checks[0].Description)
+	assert.Equal("[ $(id -u) = 1000 ]", validator.Checks[0].Probe.Exec.Command[2])
+	assert.Equal("/hub/jovyan/", validator.Checks[1].Probe.HTTPGet.Path)
+	assert.Equal(8888, validator.Checks[1].Probe.HTTPGet.Port)
+	assert.Equal(map[string]string{"user": "jovyan", "uid": "1000", "port": "8888"}, validator.Parameters)
+}
+
+func TestParameterValues(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "notebooks.yaml")
+	assert.Nil(os.WriteFile(manifest, []byte(parameterisedManifest), 0644))
+	valuesFile := filepath.Join(dir, "values.yaml")
+	assert.Nil(os.WriteFile(valuesFile, []byte("user: alice\nport: 9999\n"), 0644))
+
+	values, err := ParameterValues([]string{valuesFile}, []string{"port=8080", "uid=1001"})
+	assert.Nil(err)
+	assert.Equal(map[string]string{"user": "alice", "port": "8080", "uid": "1001"}, values)
+
+	validator, err := LoadValidator(manifest, LoadOptions{Parameters: values})
+	if assert.Nil(err) {
+		assert.Equal("/hub/alice/", validator.Env[0].Value)
+		assert.Equal(8080, validator.Checks[1].Probe.HTTPGet.Port)
+		assert.Equal("[ $(id -u) = 1001 ]", validator.Checks[0].Probe.Exec.Command[2])
+	}
+
+	_, err = LoadValidator(manifest, LoadOptions{Parameters: map[string]string{"users": "alice"}})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.EqualError(err, "unknown parameter 'users', did you mean 'user'?")
+
+	_, err = ParameterValues(nil, []string{"user"})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.EqualError(err, "invalid parameter 'user', expected name=value")
+}
+
+func TestParameterErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := LoadValidatorFromBytes([]byte(`apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+parameters:
+  user: jovyan
+  port: 8888
+ports:
+  - port: "${{ params.port }}"
+env:
+  - name: HOME
+    value: /home/${{ params.users }}
+  - name: SHELL
+    value: ${{ env.SHELL }}
+`))
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.EqualError(err, `10:12: undefined parameter 'users', did you mean 'user'?
+12:12: unsupported expression 'env.SHELL', parameters are referenced as ${{ params.name }}`)
+
+	// Quoted references are always strings
+	_, err = LoadValidatorFromBytes([]byte(`apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+parameters:
+  port: 8888
+ports:
+  - port: "${{ params.port }}"
+`))
+	assert.EqualError(err, "6:11: ports[0].port must be an integer, not '8888'")
+}
+
+func TestParametersAcrossExtends(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := writeManifests(t, map[string]string{
+		"kubeflow.yaml": "parameters:\n  user: jovyan\nchecks:\n  - name: user\n    description: User is ${{ params.user }}\n    probe:\n      exec:\n        command: [\"true\"]\n",
+		"fork.yaml":     "extends: kubeflow.yaml\nparameters:\n  user: alice\n",
+	})
+
+	validator, err := LoadValidatorFromFile(filepath.Join(dir, "fork.yaml"))
+	if assert.Nil(err) {
+		assert.Equal("User is alice", validator.Checks[0].Description)
+	}
+
+	validator, err = LoadValidator(filepath.Join(dir, "fork.yaml"), LoadOptions{Parameters: map[string]string{"user": "bob"}})
+	if assert.Nil(err) {
+		assert.Equal("User is bob", validator.Checks[0].Description)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nvidia/container-canary/internal/exitcode"
+)
+
+// Manifests which take longer than this to download are treated as unreachable
+const fetchTimeout = 30 * time.Second
+
+var httpClient = &http.Client{Timeout: fetchTimeout}
+
+// Where verified manifests are cached, replaced in tests
+var userCacheDir = os.UserCacheDir
+
+var pinPattern = regexp.MustCompile(`^sha256=([0-9a-fA-F]{64})$`)
+
+// statusError is returned when a server responds, but not with the manifest
+type statusError struct {
+	url    string
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("failed to fetch %s: %s", e.url, e.status)
+}
+
+// fetch downloads a manifest and checks it against the digest pinned in the
+// URL fragment and its signature, if either are required
+func (l *loader) fetch(location string) ([]byte, error) {
+	url, pin, err := splitPin(location)
+	if err != nil {
+		return nil, err
+	}
+	return l.fetchVerified(url, pin, func() ([]byte, []byte, error) {
+		return l.download(url)
+	})
+}
+
+// fetchVerified gets a manifest and its signature with download and verifies
+// them. Verified manifests are cached by key so that they can still be used
+// when the server can't be reached.
+func (l *loader) fetchVerified(key string, pin string, download func() ([]byte, []byte, error)) ([]byte, error) {
+	body, signature, err := download()
+	if err != nil && unreachable(err) {
+		cachedBody, cachedSignature, cacheErr := readCache(key)
+		if cacheErr != nil {
+			return nil, err
+		}
+		body, signature = cachedBody, cachedSignature
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := l.verify(key, body, signature, pin); err != nil {
+		return nil, err
+	}
+	writeCache(key, body, signature)
+	return body, nil
+}
+
+// unreachable reports whether an error getting a manifest means the server
+// couldn't be reached, rather than that it answered without the manifest
+func unreachable(err error) bool {
+	var status *statusError
+	return exitcode.Of(err) == exitcode.RuntimeError && !errors.As(err, &status)
+}
+
+// splitPin separates the digest a URL is pinned to, as https://...#sha256=<hex>,
+// from the URL to download
+func splitPin(location string) (string, string, error) {
+	url, fragment, found := strings.Cut(location, "#")
+	if !found {
+		return url, "", nil
+	}
+	match := pinPattern.FindStringSubmatch(fragment)
+	if match == nil {
+		return "", "", exitcode.Errorf(exitcode.ConfigError, "invalid pin '#%s' in %s, expected #sha256=<64 hex digits>", fragment, location)
+	}
+	return url, strings.ToLower(match[1]), nil
+}
+
+// download gets a manifest, and its signature if manifests must be signed
+func (l *loader) download(url string) ([]byte, []byte, error) {
+	body, err := get(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	if l.publicKey == nil {
+		return body, nil, nil
+	}
+	signature, err := get(url + signatureExtension)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, signature, nil
+}
+
+func get(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.RuntimeError, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, exitcode.Wrap(exitcode.RuntimeError, &statusError{url: url, status: resp.Status})
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.RuntimeError, err)
+	}
+	return body, nil
+}
+
+func (l *loader) verify(url string, body []byte, signature []byte, pin string) error {
+	if pin != "" {
+		digest := sha256.Sum256(body)
+		if actual := hex.EncodeToString(digest[:]); actual != pin {
+			return exitcode.Errorf(exitcode.ConfigError, "%s does not match its pinned digest, expected sha256=%s but got sha256=%s", url, pin, actual)
+		}
+	}
+	if l.publicKey != nil {
+		if err := l.publicKey.verify(body, signature); err != nil {
+			return exitcode.Errorf(exitcode.ConfigError, "failed to verify the signature of %s: %s", url, err.Error())
+		}
+	}
+	return nil
+}
+
+// cachePath is where the last verified copy of a manifest is kept
+func cachePath(url string) (string, error) {
+	dir, err := userCacheDir()
+	if err != nil {
+		return "", err
+	}
+	key := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, "container-canary", "manifests", hex.EncodeToString(key[:])+".yaml"), nil
+}
+
+// readCache returns the cached copy of a manifest, which is verified again
+// before it is used as the key or pin may have changed since
+func readCache(url string) ([]byte, []byte, error) {
+	path, err := cachePath(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	signature, err := os.ReadFile(path + signatureExtension)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	return body, signature, nil
+}
+
+// writeCache keeps a copy of a manifest, failing to is not an error as the
+// cache is only used when offline
+func writeCache(url string, body []byte, signature []byte) {
+	path, err := cachePath(url)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return
+	}
+	if signature != nil {
+		_ = os.WriteFile(path+signatureExtension, signature, 0o644)
+	} else {
+		_ = os.Remove(path + signatureExtension)
+	}
+}
@@ -0,0 +1,212 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blake2b"
+)
+
+const remoteManifest = `apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+name: remote
+checks:
+  - name: shell
+    probe:
+      exec:
+        command: ["/bin/sh", "-c", "true"]
+`
+
+// serveManifests serves files from memory, and can be told to stop answering
+func serveManifests(t *testing.T, files map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func useTempCache(t *testing.T) {
+	dir := t.TempDir()
+	userCacheDir = func() (string, error) { return dir, nil }
+	t.Cleanup(func() { userCacheDir = os.UserCacheDir })
+}
+
+type minisignKey struct {
+	keyID   [8]byte
+	private ed25519.PrivateKey
+}
+
+func newMinisignKey(t *testing.T) (*minisignKey, string) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := &minisignKey{private: private}
+	copy(k.keyID[:], public[:8])
+	encoded := base64.StdEncoding.EncodeToString(append(append([]byte(minisignAlgorithm), k.keyID[:]...), public...))
+	path := filepath.Join(t.TempDir(), "canary.pub")
+	if err := os.WriteFile(path, []byte("untrusted comment: minisign public key\n"+encoded+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return k, path
+}
+
+// sign makes a prehashed signature as minisign -S does by default
+func (k *minisignKey) sign(message string) string {
+	hash := blake2b.Sum512([]byte(message))
+	sig := ed25519.Sign(k.private, hash[:])
+	trustedComment := "timestamp:1700000000\tfile:remote.yaml\thashed"
+	global := ed25519.Sign(k.private, append(append([]byte{}, sig...), trustedComment...))
+	return fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte(minisignHashedAlgorithm), k.keyID[:]...), sig...)),
+		trustedComment,
+		base64.StdEncoding.EncodeToString(global))
+}
+
+func TestFetchStatus(t *testing.T) {
+	useTempCache(t)
+	server := serveManifests(t, map[string]string{})
+
+	_, err := LoadValidatorFromURL(server.URL + "/missing.yaml")
+	assert.Equal(t, exitcode.RuntimeError, exitcode.Of(err))
+	assert.EqualError(t, err, "failed to fetch "+server.URL+"/missing.yaml: 404 Not Found")
+}
+
+func TestFetchPinned(t *testing.T) {
+	assert := assert.New(t)
+	useTempCache(t)
+	server := serveManifests(t, map[string]string{"/remote.yaml": remoteManifest})
+	digest := sha256.Sum256([]byte(remoteManifest))
+	url := server.URL + "/remote.yaml"
+
+	validator, err := LoadValidatorFromURL(url + "#sha256=" + hex.EncodeToString(digest[:]))
+	if assert.Nil(err) {
+		assert.Equal("remote", validator.Name)
+	}
+
+	_, err = LoadValidatorFromURL(url + "#sha256=" + hex.EncodeToString(make([]byte, 32)))
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "does not match its pinned digest")
+
+	_, err = LoadValidatorFromURL(url + "#md5=d41d8cd98f00b204e9800998ecf8427e")
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "expected #sha256=<64 hex digits>")
+}
+
+func TestFetchSigned(t *testing.T) {
+	assert := assert.New(t)
+	useTempCache(t)
+	key, publicKey := newMinisignKey(t)
+	otherKey, _ := newMinisignKey(t)
+	server := serveManifests(t, map[string]string{
+		"/signed.yaml":           remoteManifest,
+		"/signed.yaml.minisig":   key.sign(remoteManifest),
+		"/tampered.yaml":         remoteManifest + "  - name: extra\n",
+		"/tampered.yaml.minisig": key.sign(remoteManifest),
+		"/other.yaml":            remoteManifest,
+		"/other.yaml.minisig":    otherKey.sign(remoteManifest),
+		"/unsigned.yaml":         remoteManifest,
+	})
+	options := LoadOptions{PublicKey: publicKey}
+
+	validator, err := LoadValidator(server.URL+"/signed.yaml", options)
+	if assert.Nil(err) {
+		assert.Equal("remote", validator.Name)
+	}
+
+	_, err = LoadValidator(server.URL+"/tampered.yaml", options)
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "failed to verify the signature of "+server.URL+"/tampered.yaml: signature does not match")
+
+	_, err = LoadValidator(server.URL+"/other.yaml", options)
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "not the public key given")
+
+	_, err = LoadValidator(server.URL+"/unsigned.yaml", options)
+	assert.Equal(exitcode.RuntimeError, exitcode.Of(err))
+	assert.Contains(err.Error(), "unsigned.yaml.minisig: 404 Not Found")
+
+	// Local files are trusted
+	path := filepath.Join(t.TempDir(), "local.yaml")
+	assert.Nil(os.WriteFile(path, []byte(remoteManifest), 0o644))
+	_, err = LoadValidator(path, options)
+	assert.Nil(err)
+
+	_, err = LoadValidator(path, LoadOptions{PublicKey: path})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+	assert.Contains(err.Error(), "not a minisign public key")
+}
+
+func TestFetchCache(t *testing.T) {
+	assert := assert.New(t)
+	useTempCache(t)
+	key, publicKey := newMinisignKey(t)
+	server := serveManifests(t, map[string]string{
+		"/remote.yaml":         remoteManifest,
+		"/remote.yaml.minisig": key.sign(remoteManifest),
+	})
+	url := server.URL + "/remote.yaml"
+	_, err := LoadValidator(url, LoadOptions{PublicKey: publicKey})
+	assert.Nil(err)
+
+	// Offline runs use the last verified copy, and verify it again
+	server.Close()
+	validator, err := LoadValidator(url, LoadOptions{PublicKey: publicKey})
+	if assert.Nil(err) {
+		assert.Equal("remote", validator.Name)
+	}
+	_, err = LoadValidator(url+"#sha256="+hex.EncodeToString(make([]byte, 32)), LoadOptions{})
+	assert.Equal(exitcode.ConfigError, exitcode.Of(err))
+
+	_, err = LoadValidatorFromURL(server.URL + "/uncached.yaml")
+	assert.Equal(exitcode.RuntimeError, exitcode.Of(err))
+}
+
+func TestFetchTimeout(t *testing.T) {
+	useTempCache(t)
+	httpClient.Timeout = 50 * time.Millisecond
+	t.Cleanup(func() { httpClient.Timeout = fetchTimeout })
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	defer server.Close()
+
+	_, err := LoadValidatorFromURL(server.URL + "/slow.yaml")
+	assert.Equal(t, exitcode.RuntimeError, exitcode.Of(err))
+	assert.Contains(t, err.Error(), "Client.Timeout exceeded")
+}
@@ -0,0 +1,211 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// jsonSchema is the subset of draft-07 that describes validator manifests
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Const                string                 `json:"const,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Minimum              *int                   `json:"minimum,omitempty"`
+	Maximum              *int                   `json:"maximum,omitempty"`
+	OneOf                []*jsonSchema          `json:"oneOf,omitempty"`
+	Definitions          map[string]*jsonSchema `json:"definitions,omitempty"`
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// Values which aren't strings may also be a reference to a parameter
+const parameterPattern = `^\$\{\{.*\}\}$`
+
+var (
+	byteSizeType = reflect.TypeOf(canaryv1.ByteSize(0))
+	probeType    = reflect.TypeOf(canaryv1.Probe{})
+)
+
+// schemaRules add the constraints that validateManifest enforces, which can't
+// be read from the Go types, keyed by definition and then property
+var schemaRules = map[string]map[string]func(*jsonSchema){
+	"Validator": {
+		"apiVersion": func(s *jsonSchema) { s.Const = canaryv1.APIVersion },
+		"kind":       func(s *jsonSchema) { s.Const = canaryv1.ValidatorKind },
+		"parameters": func(s *jsonSchema) {
+			s.AdditionalProperties = &jsonSchema{Type: []string{"string", "number", "boolean"}}
+		},
+	},
+	"Check": {
+		"severity": func(s *jsonSchema) { s.Enum = severities },
+	},
+	"Probe": {
+		"initialDelaySeconds":           minimum(0),
+		"timeoutSeconds":                minimum(0),
+		"periodSeconds":                 minimum(0),
+		"terminationGracePeriodSeconds": minimum(0),
+		"successThreshold":              minimum(1),
+		"failureThreshold":              minimum(1),
+	},
+	"HTTPGetAction": {
+		"port":   portRange,
+		"scheme": func(s *jsonSchema) { s.Enum = []string{"HTTP", "HTTPS"} },
+	},
+	"TCPSocketAction": {"port": portRange},
+	"ServicePort":     {"port": portRange},
+	"SecurityAction": {
+		"rule": func(s *jsonSchema) { s.Enum = securityRules },
+	},
+}
+
+// schemaRequired lists the properties of each definition which must be set
+var schemaRequired = map[string][]string{
+	"Validator":       {"apiVersion", "kind"},
+	"Check":           {"probe"},
+	"HTTPGetAction":   {"port"},
+	"TCPSocketAction": {"port"},
+	"SecurityAction":  {"rule"},
+	"Volume":          {"mountPath"},
+}
+
+func minimum(n int) func(*jsonSchema) {
+	return func(s *jsonSchema) { s.Minimum = intPtr(n) }
+}
+
+func portRange(s *jsonSchema) {
+	s.Minimum, s.Maximum = intPtr(1), intPtr(65535)
+}
+
+// schemaGenerator builds a schema from the same field names the strict
+// decoder uses, so that anything it accepts the schema accepts too
+type schemaGenerator struct {
+	definitions map[string]*jsonSchema
+	names       map[reflect.Type]string
+}
+
+// schema generates a JSON Schema for validator manifests, for editors to
+// offer completion and report mistakes as manifests are written
+func schema() *jsonSchema {
+	g := &schemaGenerator{definitions: map[string]*jsonSchema{}, names: map[reflect.Type]string{}}
+	root := g.schemaFor(reflect.TypeOf(canaryv1.Validator{}))
+	return &jsonSchema{
+		Schema:      jsonSchemaDraft,
+		Ref:         root.Ref,
+		Title:       "Container Canary Validator",
+		Description: "A manifest describing the checks a container image must pass to run on a platform.",
+		Definitions: g.definitions,
+	}
+}
+
+// WriteSchema writes the validator manifest schema as indented JSON
+func WriteSchema(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(schema())
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == byteSizeType {
+		return &jsonSchema{
+			Type:        []string{"integer", "string"},
+			Pattern:     `^\s*[0-9]+(\.[0-9]+)?\s*([KMGT]i?B?|B)?\s*$|` + parameterPattern,
+			Description: "A size in bytes, optionally with a unit such as 500MB or 2GiB.",
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return &jsonSchema{Ref: "#/definitions/" + g.define(t)}
+	case reflect.Slice:
+		return &jsonSchema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: []string{"integer", "string"}, Pattern: parameterPattern}
+	case reflect.Bool:
+		return &jsonSchema{Type: []string{"boolean", "string"}, Pattern: parameterPattern}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	default:
+		return &jsonSchema{}
+	}
+}
+
+// define adds a struct to the definitions once, so that recursive and shared
+// types are only described once
+func (g *schemaGenerator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.definitions[name]; taken {
+		name = fmt.Sprintf("%s.%s", path.Base(t.PkgPath()), t.Name())
+	}
+	g.names[t] = name
+
+	s := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}, AdditionalProperties: false}
+	g.definitions[name] = s
+	fields := yamlFields(t)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		property := g.schemaFor(fields[key].Type)
+		if rule, ok := schemaRules[name][key]; ok {
+			rule(property)
+		}
+		s.Properties[key] = property
+	}
+	s.Required = schemaRequired[name]
+	if t == probeType {
+		// Every action is a pointer, and exactly one of them must be set
+		for _, key := range keys {
+			if fields[key].Type.Kind() == reflect.Pointer {
+				s.OneOf = append(s.OneOf, &jsonSchema{Required: []string{key}})
+			}
+		}
+	}
+	return name
+}
@@ -0,0 +1,107 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// The committed schema is what editors are pointed at, regenerate it with
+// 'make schema' after changing the manifest types
+func TestSchemaIsUpToDate(t *testing.T) {
+	committed, err := os.ReadFile("../../schema/validator.schema.json")
+	assert.Nil(t, err)
+
+	b := new(bytes.Buffer)
+	assert.Nil(t, WriteSchema(b))
+	assert.Equal(t, string(committed), b.String(), "schema/validator.schema.json is out of date, run 'make schema'")
+}
+
+func TestSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	s := schema()
+	assert.Equal("#/definitions/Validator", s.Ref)
+	validator := s.Definitions["Validator"]
+	assert.Equal(false, validator.AdditionalProperties)
+	assert.Equal("container-canary.nvidia.com/v1", validator.Properties["apiVersion"].Const)
+	assert.Equal("#/definitions/EnvVar", validator.Properties["env"].Items.Ref)
+	assert.Equal("#/definitions/ServicePort", validator.Properties["ports"].Items.Ref)
+	assert.Equal([]string{"apiVersion", "kind"}, validator.Required)
+
+	probe := s.Definitions["Probe"]
+	assert.Len(probe.OneOf, 8)
+	assert.Equal("#/definitions/ExecAction", probe.Properties["exec"].Ref)
+	assert.Equal(1, *probe.Properties["failureThreshold"].Minimum)
+	assert.Equal(65535, *s.Definitions["HTTPGetAction"].Properties["port"].Maximum)
+	assert.Equal(securityRules, s.Definitions["SecurityAction"].Properties["rule"].Enum)
+	assert.Equal([]string{"integer", "string"}, s.Definitions["ImageSizeAction"].Properties["maxSize"].Type)
+
+	assert.Contains(s.Definitions["ExecAction"].Properties, "command")
+	assert.Contains(s.Definitions["EnvVar"].Properties, "value")
+}
+
+// Every field used in the examples must be described by the schema
+func TestSchemaCoversExamples(t *testing.T) {
+	s := schema()
+	paths, err := filepath.Glob("../../examples/*.yaml")
+	assert.Nil(t, err)
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		var doc yaml.Node
+		assert.Nil(t, yaml.Unmarshal(b, &doc))
+		for _, key := range unknownSchemaKeys(s, s, doc.Content[0], "") {
+			t.Errorf("%s: %s is not in the schema", path, key)
+		}
+	}
+}
+
+func unknownSchemaKeys(root, s *jsonSchema, node *yaml.Node, path string) []string {
+	if s.Ref != "" {
+		s = root.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+	}
+	var unknown []string
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			property, ok := s.Properties[key]
+			if !ok {
+				property, ok = s.AdditionalProperties.(*jsonSchema)
+			}
+			if !ok {
+				unknown = append(unknown, joinPath(path, key))
+				continue
+			}
+			unknown = append(unknown, unknownSchemaKeys(root, property, node.Content[i+1], joinPath(path, key))...)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			unknown = append(unknown, unknownSchemaKeys(root, s.Items, item, path+"[]")...)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,209 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// strictDecoder checks a YAML document against the type it will be decoded
+// into before decoding it, so that misspelt fields and values of the wrong
+// type are reported with their position rather than silently ignored.
+type strictDecoder struct {
+	errs ManifestErrors
+	// Value nodes by their path in the document, e.g. checks[0].probe.httpGet.port
+	nodes map[string]*yaml.Node
+}
+
+func newStrictDecoder() *strictDecoder {
+	return &strictDecoder{nodes: map[string]*yaml.Node{}}
+}
+
+// Types which parse their own YAML, such as sizes which may be written as "2GiB"
+var (
+	unmarshalerType       = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	legacyUnmarshalerType = reflect.TypeOf((*interface {
+		UnmarshalYAML(func(interface{}) error) error
+	})(nil)).Elem()
+)
+
+func hasUnmarshaler(t reflect.Type) bool {
+	p := reflect.PointerTo(t)
+	return p.Implements(unmarshalerType) || p.Implements(legacyUnmarshalerType)
+}
+
+func (d *strictDecoder) check(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	d.nodes[path] = node
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	if hasUnmarshaler(t) && t.Kind() != reflect.Struct {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			d.errs = append(d.errs, errorAt(node, "%s must be a mapping, not %s", describePath(path), describeNode(node)))
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				d.errs = append(d.errs, errorAt(key, "unknown field '%s' in %s%s", key.Value, describePath(path), suggest(key.Value, fields)))
+				continue
+			}
+			d.check(value, field.Type, joinPath(path, key.Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			d.errs = append(d.errs, errorAt(node, "%s must be a list, not %s", describePath(path), describeNode(node)))
+			return
+		}
+		for i, item := range node.Content {
+			d.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			d.errs = append(d.errs, errorAt(node, "%s must be a mapping, not %s", describePath(path), describeNode(node)))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			d.check(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			d.errs = append(d.errs, errorAt(node, "%s must be an integer, not %s", describePath(path), describeNode(node)))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			d.errs = append(d.errs, errorAt(node, "%s must be true or false, not %s", describePath(path), describeNode(node)))
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			d.errs = append(d.errs, errorAt(node, "%s must be a string, not %s", describePath(path), describeNode(node)))
+		}
+	}
+}
+
+// yamlFields maps the keys of a struct to its fields, using the same names as
+// the YAML library: the yaml tag, or the lowercased field name without one
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// suggest finds a field the user may have meant, for typos such as 'httpget'
+// or 'failureTreshold'
+func suggest(key string, fields map[string]reflect.StructField) string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	return suggestName(key, names)
+}
+
+// suggestName finds the closest name to key, if any are close enough to be a typo
+func suggestName(key string, names []string) string {
+	names = slices.Clone(names)
+	sort.Strings(names)
+	best, bestDistance := "", 3
+	for _, name := range names {
+		distance := editDistance(strings.ToLower(name), strings.ToLower(key))
+		if distance < bestDistance && distance < len(key)/2 {
+			best, bestDistance = name, distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean '%s'?", best)
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "the manifest"
+	}
+	return path
+}
+
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("'%s'", node.Value)
+	}
+}
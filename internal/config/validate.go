@@ -0,0 +1,177 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"gopkg.in/yaml.v3"
+)
+
+var severities = []string{
+	canaryv1.SeverityError,
+	canaryv1.SeverityWarning,
+	canaryv1.SeverityInfo,
+}
+
+var securityRules = []string{
+	canaryv1.SecurityRuleNoSetuid,
+	canaryv1.SecurityRuleNoWorldWritable,
+	canaryv1.SecurityRuleNonRootPid1,
+	canaryv1.SecurityRuleNoSecrets,
+	canaryv1.SecurityRuleNoSSHServer,
+}
+
+// manifestValidator checks a decoded validator for values which are well
+// formed YAML but make no sense, reporting them at their position
+type manifestValidator struct {
+	errs  ManifestErrors
+	nodes map[string]*yaml.Node
+}
+
+// at finds the node for a path, or the closest parent that was written out
+func (m *manifestValidator) at(path string) *yaml.Node {
+	for {
+		if node, ok := m.nodes[path]; ok {
+			return node
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			return m.nodes[""]
+		}
+		path = path[:i]
+	}
+}
+
+func (m *manifestValidator) errorf(path string, format string, a ...interface{}) {
+	m.errs = append(m.errs, errorAt(m.at(path), format, a...))
+}
+
+func validateManifest(v *canaryv1.Validator, nodes map[string]*yaml.Node) ManifestErrors {
+	m := &manifestValidator{nodes: nodes}
+
+	if v.APIVersion == "" {
+		m.errorf("", "missing apiVersion, expected '%s'", canaryv1.APIVersion)
+	} else if v.APIVersion != canaryv1.APIVersion {
+		m.errorf("apiVersion", "unsupported apiVersion '%s', expected '%s'", v.APIVersion, canaryv1.APIVersion)
+	}
+	if v.Kind == "" {
+		m.errorf("", "missing kind, expected '%s'", canaryv1.ValidatorKind)
+	} else if v.Kind != canaryv1.ValidatorKind {
+		m.errorf("kind", "unsupported kind '%s', expected '%s'", v.Kind, canaryv1.ValidatorKind)
+	}
+
+	for i, port := range v.Ports {
+		m.checkPort(fmt.Sprintf("ports[%d].port", i), int(port.Port))
+	}
+
+	names := map[string]string{}
+	for i, check := range v.Checks {
+		path := fmt.Sprintf("checks[%d]", i)
+		if check.Name == "" && (v.Extends != "" || len(v.Include) > 0) {
+			m.errorf(path, "check has no name, checks must be named in a manifest which extends or includes others")
+		}
+		if check.Name != "" {
+			if first, ok := names[check.Name]; ok {
+				m.errorf(path+".name", "duplicate check name '%s', first used on line %d", check.Name, m.at(first).Line)
+			} else {
+				names[check.Name] = path + ".name"
+			}
+		}
+		if check.Severity != "" && !slices.Contains(severities, check.Severity) {
+			m.errorf(path+".severity", "unknown severity '%s', must be one of %s", check.Severity, strings.Join(severities, ", "))
+		}
+		m.checkProbe(path+".probe", check.Name, &check.Probe)
+	}
+
+	sort.SliceStable(m.errs, func(i, j int) bool {
+		if m.errs[i].Line != m.errs[j].Line {
+			return m.errs[i].Line < m.errs[j].Line
+		}
+		return m.errs[i].Column < m.errs[j].Column
+	})
+	return m.errs
+}
+
+func (m *manifestValidator) checkPort(path string, port int) {
+	if port < 1 || port > 65535 {
+		m.errorf(path, "port %d is out of range, it must be between 1 and 65535", port)
+	}
+}
+
+func (m *manifestValidator) checkProbe(path string, name string, probe *canaryv1.Probe) {
+	actions := 0
+	for _, set := range []bool{
+		probe.Exec != nil,
+		probe.HTTPGet != nil,
+		probe.TCPSocket != nil,
+		probe.ImageSize != nil,
+		probe.Packages != nil,
+		probe.Security != nil,
+		probe.ReadOnlyRootFilesystem != nil,
+		probe.GroupWritable != nil,
+	} {
+		if set {
+			actions++
+		}
+	}
+	if actions == 0 {
+		m.errorf(path, "check '%s' has no probe", name)
+	} else if actions > 1 {
+		m.errorf(path, "check '%s' has %d probes, only one is allowed", name, actions)
+	}
+
+	for _, field := range []struct {
+		name  string
+		value int
+	}{
+		{"initialDelaySeconds", probe.InitialDelaySeconds},
+		{"timeoutSeconds", probe.TimeoutSeconds},
+		{"periodSeconds", probe.PeriodSeconds},
+		{"terminationGracePeriodSeconds", probe.TerminationGracePeriodSeconds},
+	} {
+		if field.value < 0 {
+			m.errorf(path+"."+field.name, "%s must not be negative", field.name)
+		}
+	}
+	for _, field := range []struct {
+		name  string
+		value int
+	}{
+		{"successThreshold", probe.SuccessThreshold},
+		{"failureThreshold", probe.FailureThreshold},
+	} {
+		if field.value < 1 {
+			m.errorf(path+"."+field.name, "%s must be at least 1", field.name)
+		}
+	}
+
+	if probe.HTTPGet != nil {
+		m.checkPort(path+".httpGet.port", probe.HTTPGet.Port)
+	}
+	if probe.TCPSocket != nil {
+		m.checkPort(path+".tcpSocket.port", probe.TCPSocket.Port)
+	}
+	if probe.Security != nil && !slices.Contains(securityRules, probe.Security.Rule) {
+		m.errorf(path+".security.rule", "unknown security rule '%s', must be one of %s", probe.Security.Rule, strings.Join(securityRules, ", "))
+	}
+}
@@ -19,6 +19,8 @@ package container
 
 import (
 	"fmt"
+	"os"
+	"sync"
 
 	"github.com/google/uuid"
 	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
@@ -26,25 +28,152 @@ import (
 )
 
 type ContainerState struct {
-	Status  string
-	Running bool
+	Status    string
+	Running   bool
+	Pid       int
+	ExitCode  int
+	OOMKilled bool
+	// Set by the runtime when the container could not be run
+	Error  string
+	Health *ContainerHealth
+}
+
+// ContainerHealth is only reported for images with a HEALTHCHECK
+type ContainerHealth struct {
+	Status        string
+	FailingStreak int
+}
+
+type ContainerConfig struct {
+	User string
+	Env  []string
 }
 
 type ContainerInfo struct {
 	Id         string
+	Name       string
 	State      ContainerState
+	Config     ContainerConfig
 	RunCommand string
 }
 
+type ImageRootFS struct {
+	Type   string
+	Layers []string
+}
+
+type ImageInfo struct {
+	Id           string
+	RepoTags     []string
+	RepoDigests  []string
+	Size         int64
+	Os           string
+	Architecture string
+	RootFS       ImageRootFS
+}
+
+// ImageLayer is an entry in the image history, which may or may not have
+// produced a filesystem layer.
+type ImageLayer struct {
+	Id        string
+	CreatedBy string
+	Size      int64
+}
+
+// FileInfo describes an entry in the container filesystem
+type FileInfo struct {
+	Path string
+	Mode os.FileMode
+	Uid  int
+	Gid  int
+	Size int64
+	// The first few hundred bytes of small regular files, for sniffing their contents.
+	Head []byte
+}
+
+// FileChange is a path which has been added (A), changed (C) or deleted (D)
+// in the container filesystem since it started
+type FileChange struct {
+	Kind string
+	Path string
+}
+
+type Process struct {
+	Pid     int
+	Uid     int
+	Command string
+}
+
 type ContainerInterface interface {
 	Start(timeoutSeconds int) error
 	Remove() error
 	Status() (*ContainerInfo, error)
 	Exec(command ...string) (string, error)
 	Logs() (string, error)
+	InspectImage() (*ImageInfo, error)
+	ImageHistory() ([]ImageLayer, error)
+	CompressedImageSize() (int64, error)
+	ReadFiles(path string) (map[string][]byte, error)
+	Files() ([]FileInfo, error)
+	Processes() ([]Process, error)
+	Diff() ([]FileChange, error)
 }
 
-func New(image string, env []v1.EnvVar, ports []v1.ServicePort, volumes []canaryv1.Volume, command []string, dockerRunOptions []string) ContainerInterface {
+// Diagnostics describe a container to help work out why it failed
+type Diagnostics struct {
+	RunCommand string
+	State      ContainerState
+	Logs       string
+	// Paths a writable copy of a container with a read-only root filesystem
+	// changed, when the read-only container failed to start.
+	Writes []FileChange
+}
+
+// CollectDiagnostics gathers what it can about a container, skipping anything
+// that fails because the container has gone away
+func CollectDiagnostics(c ContainerInterface) *Diagnostics {
+	d := &Diagnostics{}
+	if info, err := c.Status(); err == nil {
+		d.RunCommand = info.RunCommand
+		d.State = info.State
+	}
+	if logs, err := c.Logs(); err == nil {
+		d.Logs = logs
+	}
+	return d
+}
+
+// StartError is returned when a container fails to start, along with anything
+// that was collected about it before it was removed
+type StartError struct {
+	Err         error
+	Diagnostics *Diagnostics
+}
+
+func (e *StartError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
+
+func New(image string, env []v1.EnvVar, ports []v1.ServicePort, volumes []canaryv1.Volume, command []string, dockerRunOptions []string, securityContext *canaryv1.SecurityContext) ContainerInterface {
 	name := fmt.Sprintf("%s%s", "canary-runner-", uuid.New().String()[:8])
-	return &DockerContainer{Name: name, Image: image, Command: command, Env: env, Ports: ports, Volumes: volumes, RunOptions: dockerRunOptions}
+	return &DockerContainer{Name: name, Image: image, Command: command, Env: env, Ports: ports, Volumes: volumes, RunOptions: dockerRunOptions, SecurityContext: securityContext, files: &fileIndex{}, writes: &changeIndex{}}
+}
+
+// fileIndex caches a listing of the container filesystem, which is expensive to build
+type fileIndex struct {
+	once  sync.Once
+	files []FileInfo
+	err   error
+}
+
+// changeIndex caches the changes made by a writable copy of a read-only
+// container, which takes a while to find
+type changeIndex struct {
+	once    sync.Once
+	changes []FileChange
+	err     error
 }
@@ -1,28 +1,44 @@
 package container
 
 import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"os/exec"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/exitcode"
 	v1 "k8s.io/api/core/v1"
 )
 
 type DockerContainer struct {
-	Name       string
-	Id         string
-	Image      string
-	Command    []string
-	Env        []v1.EnvVar
-	Ports      []v1.ServicePort
-	Volumes    []canaryv1.Volume
-	RunOptions []string
-	runCommand string
-	StartupTimeout int
+	Name            string
+	Id              string
+	Image           string
+	Command         []string
+	Env             []v1.EnvVar
+	Ports           []v1.ServicePort
+	Volumes         []canaryv1.Volume
+	RunOptions      []string
+	SecurityContext *canaryv1.SecurityContext
+	runCommand      string
+	files           *fileIndex
+	writes          *changeIndex
+	started         time.Time
+	StartupTimeout  int
+	// Publish Ports on ephemeral host ports rather than the same port numbers,
+	// so a copy of a container can run alongside it.
+	publishAnyPort bool
 }
 
 // Start a container
@@ -37,17 +53,31 @@ func (c *DockerContainer) Start(timeoutSeconds int) error {
 	}
 
 	for _, p := range c.Ports {
-		commandArgs = append(commandArgs, "-p", fmt.Sprintf("%d:%d/%s", p.Port, p.Port, p.Protocol))
+		if c.publishAnyPort {
+			commandArgs = append(commandArgs, "-p", fmt.Sprintf("%d/%s", p.Port, p.Protocol))
+		} else {
+			commandArgs = append(commandArgs, "-p", fmt.Sprintf("%d:%d/%s", p.Port, p.Port, p.Protocol))
+		}
 	}
 
 	for _, v := range c.Volumes {
-		if v.Path != "" {
+		if v.Tmpfs {
+			commandArgs = append(commandArgs, "--tmpfs", v.MountPath)
+		} else if v.Path != "" {
 			commandArgs = append(commandArgs, "-v", fmt.Sprintf("%s:%s", v.Path, v.MountPath))
 		} else {
 			commandArgs = append(commandArgs, "-v", v.MountPath)
 		}
 	}
 
+	if c.readOnly() {
+		commandArgs = append(commandArgs, "--read-only")
+	}
+
+	if c.SecurityContext != nil && c.SecurityContext.RunAsArbitraryUser {
+		commandArgs = append(commandArgs, "--user", fmt.Sprintf("%d:0", arbitraryUID()))
+	}
+
 	if len(c.RunOptions) > 0 {
 		commandArgs = append(commandArgs, c.RunOptions...)
 	}
@@ -61,8 +91,20 @@ func (c *DockerContainer) Start(timeoutSeconds int) error {
 	if err := CheckForDocker(); err != nil {
 		return err
 	}
-	_, err := exec.Command("docker", commandArgs...).Output()
 	c.runCommand = fmt.Sprintf("docker %s", strings.Join(commandArgs, " "))
+	c.started = time.Now()
+	c.StartupTimeout = timeoutSeconds
+	if _, err := exec.Command("docker", commandArgs...).Output(); err != nil {
+		// docker run can fail after creating the container, for example when a
+		// port is already allocated, so it may or may not need removing
+		diagnostics := CollectDiagnostics(c)
+		diagnostics.RunCommand = c.runCommand
+		_ = c.Remove()
+		return &StartError{
+			Err:         exitcode.Errorf(exitcode.StartupFailure, "container failed to start: %s", commandError(err)),
+			Diagnostics: diagnostics,
+		}
+	}
 
 	for startTime := time.Now(); ; {
 		info, err := c.Status()
@@ -70,30 +112,64 @@ func (c *DockerContainer) Start(timeoutSeconds int) error {
 			return err
 		}
 		if info.State.Status == "exited" {
-			err := c.Remove()
-			if err != nil {
+			diagnostics := CollectDiagnostics(c)
+			if err := c.Remove(); err != nil {
 				return err
 			}
-			return errors.New("container failed to start")
+			// Failing to write to a read-only root filesystem is a common reason
+			// for exiting, so show where the container tries to write
+			if c.readOnly() {
+				diagnostics.Writes, _ = c.writableDiff(time.Since(c.started))
+			}
+			return &StartError{
+				Err:         exitcode.Errorf(exitcode.StartupFailure, "container failed to start, %s", exitReason(info.State)),
+				Diagnostics: diagnostics,
+			}
 		}
 		if info.State.Running {
 			break
 		}
 		if time.Since(startTime) > (time.Second * time.Duration(timeoutSeconds)) {
-			err := c.Remove()
-			if err != nil {
+			diagnostics := CollectDiagnostics(c)
+			if err := c.Remove(); err != nil {
 				return err
 			}
-			return fmt.Errorf("container failed to start after %d seconds", timeoutSeconds)
+			return &StartError{
+				Err:         exitcode.Errorf(exitcode.Timeout, "container failed to start after %d seconds", timeoutSeconds),
+				Diagnostics: diagnostics,
+			}
 		}
 		time.Sleep(time.Second)
 	}
 
-	if err != nil {
-		return err
+	return nil
+}
+
+// exitReason describes why a container stopped
+func exitReason(state ContainerState) string {
+	if state.OOMKilled {
+		return "it was killed after running out of memory"
 	}
+	if state.Error != "" {
+		return state.Error
+	}
+	return fmt.Sprintf("it exited with code %d", state.ExitCode)
+}
 
-	return nil
+// commandError includes what a docker command wrote to stderr in its error
+func commandError(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if stderr := strings.TrimSpace(string(exitErr.Stderr)); stderr != "" {
+			return stderr
+		}
+	}
+	return err.Error()
+}
+
+// OpenShift allocates each project a block of 10000 UIDs starting above 1000000000
+func arbitraryUID() int {
+	return 1000000000 + rand.Intn(100000)*10000 + rand.Intn(10000)
 }
 
 func CheckForDocker() error {
@@ -149,8 +225,368 @@ func (c DockerContainer) Exec(command ...string) (string, error) {
 	return string(out), err
 }
 
-// Get container logs
+// Get container logs, interleaving stdout and stderr
 func (c DockerContainer) Logs() (string, error) {
-	out, err := exec.Command("docker", "logs", c.Name).Output()
+	out, err := exec.Command("docker", "logs", c.Name).CombinedOutput()
 	return string(out), err
 }
+
+// Read a file, or every file in a directory, from the container filesystem.
+// This works without any binaries in the container so can be used on distroless images.
+func (c DockerContainer) ReadFiles(path string) (map[string][]byte, error) {
+	output, err := exec.Command("docker", "cp", fmt.Sprintf("%s:%s", c.Name, path), "-").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	archive := tar.NewReader(bytes.NewReader(output))
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		contents, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = contents
+	}
+	return files, nil
+}
+
+// Longest time to let a writable copy of a read-only container run before comparing its filesystem
+const maxDiffSeconds = 60
+
+// List the paths which have been changed in the container filesystem.
+// Nothing can be written to a read-only root filesystem, so in that case a
+// writable copy of the container is run for as long as this one has been
+// running to see where it tries to write. The copy is only run once and its
+// changes are reused by later calls.
+func (c *DockerContainer) Diff() ([]FileChange, error) {
+	if !c.readOnly() {
+		return c.diff()
+	}
+	if c.writes == nil {
+		c.writes = &changeIndex{}
+	}
+	c.writes.once.Do(func() {
+		c.writes.changes, c.writes.err = c.writableDiff(time.Since(c.started))
+	})
+	return c.writes.changes, c.writes.err
+}
+
+func (c DockerContainer) readOnly() bool {
+	return c.SecurityContext != nil && c.SecurityContext.ReadOnlyRootFilesystem
+}
+
+// writableDiff runs a copy of the container without a read-only root
+// filesystem for up to maxDiffSeconds, and lists the paths it changes
+func (c DockerContainer) writableDiff(wait time.Duration) ([]FileChange, error) {
+	var runOptions []string
+	for _, option := range c.RunOptions {
+		if option != "--read-only" && !strings.HasPrefix(option, "--read-only=") {
+			runOptions = append(runOptions, option)
+		}
+	}
+	writable := &DockerContainer{
+		Name:       c.Name + "-writable",
+		Image:      c.Image,
+		Command:    c.Command,
+		Env:        c.Env,
+		Ports:      c.Ports,
+		Volumes:    c.Volumes,
+		RunOptions: runOptions,
+		SecurityContext: &canaryv1.SecurityContext{
+			RunAsArbitraryUser: c.SecurityContext.RunAsArbitraryUser,
+		},
+		files:          &fileIndex{},
+		publishAnyPort: true,
+	}
+	if err := writable.Start(c.StartupTimeout); err != nil {
+		return nil, fmt.Errorf("failed to start writable copy of container: %w", err)
+	}
+	defer func() {
+		_ = writable.Remove()
+	}()
+
+	if wait > maxDiffSeconds*time.Second {
+		wait = maxDiffSeconds * time.Second
+	}
+	time.Sleep(wait - time.Since(writable.started))
+	return writable.diff()
+}
+
+func (c DockerContainer) diff() ([]FileChange, error) {
+	output, err := exec.Command("docker", "diff", c.Name).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		kind, path, ok := strings.Cut(line, " ")
+		if ok && !c.isMounted(path) {
+			changes = append(changes, FileChange{Kind: kind, Path: path})
+		}
+	}
+	return changes, nil
+}
+
+// Writes to volumes do not touch the root filesystem
+func (c DockerContainer) isMounted(path string) bool {
+	for _, v := range c.Volumes {
+		mountPath := strings.TrimSuffix(v.MountPath, "/")
+		if path == mountPath || strings.HasPrefix(path, mountPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Number of bytes of each small file to keep in the filesystem index
+const fileHeadSize = 512
+
+// List every file in the container filesystem. The listing is built once by
+// exporting the container and reused by later calls.
+func (c *DockerContainer) Files() ([]FileInfo, error) {
+	if c.files == nil {
+		c.files = &fileIndex{}
+	}
+	c.files.once.Do(func() {
+		c.files.files, c.files.err = c.exportFiles()
+	})
+	return c.files.files, c.files.err
+}
+
+func (c DockerContainer) exportFiles() ([]FileInfo, error) {
+	command := exec.Command("docker", "export", c.Name)
+	stdout, err := command.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := command.Start(); err != nil {
+		return nil, err
+	}
+
+	var files []FileInfo
+	archive := tar.NewReader(stdout)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			abort(command)
+			return nil, err
+		}
+		file := FileInfo{
+			Path: "/" + strings.TrimPrefix(path.Clean(header.Name), "/"),
+			Mode: header.FileInfo().Mode(),
+			Uid:  header.Uid,
+			Gid:  header.Gid,
+			Size: header.Size,
+		}
+		if header.Typeflag == tar.TypeReg && header.Size <= 64*1024 {
+			head := make([]byte, fileHeadSize)
+			n, err := io.ReadFull(archive, head)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				abort(command)
+				return nil, err
+			}
+			file.Head = head[:n]
+		}
+		files = append(files, file)
+	}
+
+	if err := command.Wait(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// List the processes running in the container
+func (c DockerContainer) Processes() ([]Process, error) {
+	output, err := exec.Command("docker", "top", c.Name, "-o", "pid,uid,args").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []Process
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected process id %q", fields[0])
+		}
+		uid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected user id %q", fields[1])
+		}
+		processes = append(processes, Process{Pid: pid, Uid: uid, Command: strings.Join(fields[2:], " ")})
+	}
+	return processes, nil
+}
+
+// Inspect the image the container is created from
+func (c DockerContainer) InspectImage() (*ImageInfo, error) {
+	return InspectImage(c.Image)
+}
+
+// Inspect a local image
+func InspectImage(image string) (*ImageInfo, error) {
+	output, err := exec.Command("docker", "image", "inspect", image).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var infoList []ImageInfo
+
+	err = json.Unmarshal(output, &infoList)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(infoList) != 1 {
+		return nil, fmt.Errorf("expected 1 image, got %d", len(infoList))
+	}
+
+	return &infoList[0], nil
+}
+
+// Get the history of the image the container is created from, newest first
+func (c DockerContainer) ImageHistory() ([]ImageLayer, error) {
+	output, err := exec.Command("docker", "image", "history", "--no-trunc", "--human=false", "--format", "{{json .}}", c.Image).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []ImageLayer
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry struct {
+			ID        string
+			CreatedBy string
+			Size      string
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, err
+		}
+		size, err := strconv.ParseInt(entry.Size, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected layer size %q", entry.Size)
+		}
+		layers = append(layers, ImageLayer{Id: entry.ID, CreatedBy: entry.CreatedBy, Size: size})
+	}
+	return layers, scanner.Err()
+}
+
+// Estimate the compressed size of the image by gzipping each layer as a registry push would
+func (c DockerContainer) CompressedImageSize() (int64, error) {
+	command := exec.Command("docker", "image", "save", c.Image)
+	stdout, err := command.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := command.Start(); err != nil {
+		return 0, err
+	}
+
+	total, err := compressedLayerSize(stdout)
+	if err != nil {
+		abort(command)
+		return 0, err
+	}
+	if err := command.Wait(); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// compressedLayerSize adds up the gzipped size of the layers listed in the
+// manifest.json of a 'docker save' archive. The manifest can come after the
+// layers, so every layer-like entry is compressed, and the config and any other
+// blobs that aren't layers are left out at the end.
+func compressedLayerSize(r io.Reader) (int64, error) {
+	sizes := map[string]int64{}
+	var manifest []struct {
+		Layers []string
+	}
+	foundManifest := false
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if name == "manifest.json" {
+			if err := json.NewDecoder(archive).Decode(&manifest); err != nil {
+				return 0, fmt.Errorf("unexpected manifest.json in image archive: %w", err)
+			}
+			foundManifest = true
+			continue
+		}
+		if !strings.HasSuffix(name, "layer.tar") && !strings.HasPrefix(name, "blobs/") {
+			continue
+		}
+		counter := &countingWriter{}
+		compressor := gzip.NewWriter(counter)
+		if _, err := io.Copy(compressor, archive); err != nil {
+			return 0, err
+		}
+		if err := compressor.Close(); err != nil {
+			return 0, err
+		}
+		sizes[name] = counter.n
+	}
+	if !foundManifest {
+		return 0, errors.New("image archive has no manifest.json")
+	}
+
+	var total int64
+	counted := map[string]bool{}
+	for _, image := range manifest {
+		for _, layer := range image.Layers {
+			layer = path.Clean(layer)
+			if !counted[layer] {
+				counted[layer] = true
+				total += sizes[layer]
+			}
+		}
+	}
+	return total, nil
+}
+
+// abort kills a command whose output is no longer being read, as otherwise it
+// blocks forever writing to a full pipe, and waits for it to exit
+func abort(command *exec.Cmd) {
+	_ = command.Process.Kill()
+	_ = command.Wait()
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
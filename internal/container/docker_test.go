@@ -18,8 +18,12 @@
 package container
 
 import (
+	"archive/tar"
+	"bytes"
+	"os/exec"
 	"strings"
 	"testing"
+	"time"
 
 	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
 	"github.com/stretchr/testify/assert"
@@ -37,7 +41,7 @@ func TestDockerContainer(t *testing.T) {
 	volumes := []canaryv1.Volume{
 		{MountPath: "/foo"},
 	}
-	c := New("nginx", env, ports, volumes, nil, nil)
+	c := New("nginx", env, ports, volumes, nil, nil, nil)
 
 	err := c.Start(10)
 
@@ -69,7 +73,7 @@ func TestDockerContainer(t *testing.T) {
 	}
 }
 func TestDockerContainerRemoves(t *testing.T) {
-	c := New("nginx", nil, nil, nil, nil, nil)
+	c := New("nginx", nil, nil, nil, nil, nil, nil)
 
 	err := c.Start(10)
 	if err != nil {
@@ -83,3 +87,62 @@ func TestDockerContainerRemoves(t *testing.T) {
 		return
 	}
 }
+
+func TestAbort(t *testing.T) {
+	// yes fills its stdout pipe and blocks, as docker save does when its
+	// output stops being read
+	command := exec.Command("yes")
+	_, err := command.StdoutPipe()
+	assert.Nil(t, err)
+	assert.Nil(t, command.Start())
+
+	done := make(chan struct{})
+	go func() {
+		abort(command)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("abort did not return")
+	}
+}
+
+func TestCompressedLayerSize(t *testing.T) {
+	assert := assert.New(t)
+
+	layer := bytes.Repeat([]byte("a"), 64*1024)
+	config := bytes.Repeat([]byte("b"), 64*1024)
+	saved := func(entries ...[2]string) *bytes.Buffer {
+		b := new(bytes.Buffer)
+		archive := tar.NewWriter(b)
+		for _, entry := range entries {
+			assert.Nil(archive.WriteHeader(&tar.Header{Name: entry[0], Mode: 0o644, Size: int64(len(entry[1])), Typeflag: tar.TypeReg}))
+			_, err := archive.Write([]byte(entry[1]))
+			assert.Nil(err)
+		}
+		assert.Nil(archive.Close())
+		return b
+	}
+
+	layerOnly, err := compressedLayerSize(saved(
+		[2]string{"blobs/sha256/aaaa", string(layer)},
+		[2]string{"manifest.json", `[{"Layers":["blobs/sha256/aaaa"]}]`},
+	))
+	assert.Nil(err)
+	assert.Greater(layerOnly, int64(0))
+	assert.Less(layerOnly, int64(len(layer)))
+
+	// An OCI layout archive from recent versions of Docker, where the manifest
+	// comes after the blobs, and the config blob isn't a layer
+	size, err := compressedLayerSize(saved(
+		[2]string{"blobs/sha256/aaaa", string(layer)},
+		[2]string{"blobs/sha256/bbbb", string(config)},
+		[2]string{"manifest.json", `[{"Config":"blobs/sha256/bbbb","Layers":["blobs/sha256/aaaa"]}]`},
+	))
+	assert.Nil(err)
+	assert.Equal(layerOnly, size)
+
+	_, err = compressedLayerSize(saved([2]string{"blobs/sha256/aaaa", string(layer)}))
+	assert.NotNil(err)
+}
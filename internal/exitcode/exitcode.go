@@ -0,0 +1,85 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+// Package exitcode defines the exit status of canary for each category of
+// failure, so automation can tell a non-compliant image from a broken run.
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Code int
+
+const (
+	// The image passed validation
+	OK Code = 0
+	// One or more checks failed, the image does not meet the validator
+	ChecksFailed Code = 1
+	// The manifest or command line arguments are invalid
+	ConfigError Code = 2
+	// Canary couldn't run, for example Docker is missing, the image or
+	// manifest couldn't be fetched or a report couldn't be written
+	RuntimeError Code = 3
+	// The container exited or was removed before checks could run
+	StartupFailure Code = 4
+	// The container didn't start in time or a check timed out
+	Timeout Code = 5
+	// Validation was stopped by the user, following the shell convention of 128+SIGINT
+	Interrupted Code = 130
+)
+
+// Error attaches an exit code to an error
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap attaches an exit code to err, returning nil if err is nil
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Errorf formats an error with an exit code
+func Errorf(code Code, format string, a ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, a...)}
+}
+
+// Of returns the exit code for an error. Errors without one are treated as
+// runtime errors as they are most likely to come from the environment.
+func Of(err error) Code {
+	if err == nil {
+		return OK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return RuntimeError
+}
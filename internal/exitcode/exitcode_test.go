@@ -0,0 +1,40 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOf(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(OK, Of(nil))
+	assert.Equal(RuntimeError, Of(errors.New("Docker is missing")))
+	assert.Equal(ConfigError, Of(Errorf(ConfigError, "no checks found")))
+
+	// Codes survive being wrapped again
+	err := fmt.Errorf("loading manifest: %w", Wrap(Timeout, errors.New("timed out")))
+	assert.Equal(Timeout, Of(err))
+	assert.Equal("loading manifest: timed out", err.Error())
+
+	assert.Nil(Wrap(ConfigError, nil))
+}
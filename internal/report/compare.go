@@ -0,0 +1,163 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Change describes how a check's outcome differs between two reports
+type Change string
+
+const (
+	ChangeRegressed Change = "regressed"
+	ChangeFixed     Change = "fixed"
+	ChangeUnchanged Change = "unchanged"
+	ChangeNew       Change = "new"
+	ChangeRemoved   Change = "removed"
+)
+
+type CheckComparison struct {
+	Name        string
+	Description string
+	Change      Change
+	// Empty for new and removed checks respectively
+	OldStatus Status
+	NewStatus Status
+}
+
+// Comparison lists the checks of a new report alongside the same checks in an
+// older one, followed by any checks which are no longer run
+type Comparison struct {
+	Old    *Report
+	New    *Report
+	Checks []CheckComparison
+}
+
+// Compare matches checks by name between two reports. A check regresses when it
+// passed before and now does not, whether it failed, errored or was skipped.
+// Warning and info checks never regress, as they can't fail validation, and
+// checks which either run deselected with --only or --skip are unchanged, as
+// there is nothing to compare. A blocking check which passed before and is
+// missing from the new report has regressed too, as that is what a run which
+// failed before it got to the checks looks like.
+func Compare(oldReport *Report, newReport *Report) *Comparison {
+	c := &Comparison{Old: oldReport, New: newReport}
+	previous := map[string]Check{}
+	for _, check := range oldReport.Checks {
+		previous[check.key()] = check
+	}
+	for _, check := range newReport.Checks {
+		comparison := CheckComparison{Name: check.Name, Description: check.Description, NewStatus: check.Status}
+		before, ok := previous[check.key()]
+		delete(previous, check.key())
+		switch {
+		case !ok:
+			comparison.Change = ChangeNew
+		case before.deselected() || check.deselected():
+			comparison.Change = ChangeUnchanged
+		case before.Status == StatusPassed && check.Status != StatusPassed && check.blocking():
+			comparison.Change = ChangeRegressed
+		case before.Status != StatusPassed && check.Status == StatusPassed:
+			comparison.Change = ChangeFixed
+		default:
+			comparison.Change = ChangeUnchanged
+		}
+		comparison.OldStatus = before.Status
+		c.Checks = append(c.Checks, comparison)
+	}
+	for _, check := range oldReport.Checks {
+		if _, ok := previous[check.key()]; ok {
+			change := ChangeRemoved
+			if check.Status == StatusPassed && check.blocking() {
+				change = ChangeRegressed
+			}
+			c.Checks = append(c.Checks, CheckComparison{Name: check.Name, Description: check.Description, Change: change, OldStatus: check.Status})
+		}
+	}
+	return c
+}
+
+// deselected is whether the check was skipped because it wasn't selected to run,
+// rather than because validation failed before it could
+func (c Check) deselected() bool {
+	return c.Status == StatusSkipped && c.Reason != ""
+}
+
+// Checks are matched by name, which older manifests may have left empty
+func (c Check) key() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Description
+}
+
+// Count returns the number of checks with a change
+func (c *Comparison) Count(change Change) int {
+	n := 0
+	for _, check := range c.Checks {
+		if check.Change == change {
+			n++
+		}
+	}
+	return n
+}
+
+// Write prints the comparison as a table, one line per check
+func (c *Comparison) Write(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Comparing %s with %s against %s\n", c.Old.Image.Name, c.New.Image.Name, c.New.Validator.Name)
+	if c.New.Error != "" {
+		fmt.Fprintf(&b, "Validation of %s did not finish: %s\n", c.New.Image.Name, c.New.Error)
+	}
+	for _, check := range c.Checks {
+		title := check.Description
+		if title == "" {
+			title = check.Name
+		}
+		var status string
+		switch check.Change {
+		case ChangeNew:
+			status = string(check.NewStatus)
+		case ChangeRemoved, ChangeUnchanged:
+			status = string(check.OldStatus)
+			if check.NewStatus != "" && check.NewStatus != check.OldStatus {
+				status = fmt.Sprintf("%s -> %s", check.OldStatus, check.NewStatus)
+			}
+		default:
+			newStatus := string(check.NewStatus)
+			if newStatus == "" {
+				newStatus = "missing"
+			}
+			status = fmt.Sprintf("%s -> %s", check.OldStatus, newStatus)
+		}
+		fmt.Fprintf(&b, " %-10s %-50s [%s]\n", check.Change, title, status)
+	}
+
+	var counts []string
+	for _, change := range []Change{ChangeRegressed, ChangeFixed, ChangeUnchanged, ChangeNew, ChangeRemoved} {
+		if n := c.Count(change); n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, change))
+		}
+	}
+	fmt.Fprintln(&b, strings.Join(counts, ", "))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
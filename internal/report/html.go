@@ -0,0 +1,95 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package report
+
+import (
+	"html/template"
+	"io"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"icon":     func(outcome string) string { return statusIcons[outcome] },
+	"duration": junitTime,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Image.Name}} - {{.Validator.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 0.4em 0.8em; text-align: left; vertical-align: top; }
+tr.passed { background: #e6ffec; }
+tr.failed, tr.error { background: #ffebe9; }
+tr.warning { background: #fff8c5; }
+tr.skipped, tr.info { color: #57606a; }
+pre { margin: 0.4em 0 0; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{if .Passed}}{{icon "passed"}}{{else}}{{icon "failed"}}{{end}} {{.Image.Name}}</h1>
+<p>Validated against {{if .Validator.Documentation}}<a href="{{.Validator.Documentation}}">{{.Validator.Name}}</a>{{else}}{{.Validator.Name}}{{end}}{{with .Validator.Description}} ({{.}}){{end}}: {{.Summary}}.</p>
+{{- with .Error}}
+<p><strong>Error:</strong> {{.}}</p>
+{{- end}}
+<table>
+<thead>
+<tr><th></th><th>Check</th><th>Status</th><th>Duration</th><th>Error</th></tr>
+</thead>
+<tbody>
+{{- range .Checks}}
+<tr class="{{.Outcome}}">
+<td>{{icon .Outcome}}</td>
+<td>{{.Title}}{{if .Output}}
+<details><summary>Output</summary><pre>{{.Output}}</pre></details>{{end}}</td>
+<td>{{.Outcome}}</td>
+<td>{{duration .DurationSeconds}}s</td>
+<td>{{.Error}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+{{- with .Diagnostics}}
+<h2>Container diagnostics</h2>
+<details><summary>Run command, status and logs</summary><pre>{{.String}}</pre></details>
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlReport exposes the unexported helpers to the template
+type htmlReport struct {
+	*Report
+	Summary string
+	Checks  []htmlCheck
+}
+
+type htmlCheck struct {
+	Check
+	Title   string
+	Outcome string
+}
+
+// writeHTML renders a standalone page which can be published as a static file
+func writeHTML(r *Report, w io.Writer) error {
+	data := htmlReport{Report: r, Summary: r.summary()}
+	for _, check := range r.Checks {
+		data.Checks = append(data.Checks, htmlCheck{Check: check, Title: check.title(), Outcome: check.outcome()})
+	}
+	return htmlTemplate.Execute(w, data)
+}
@@ -0,0 +1,159 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+	SystemErr  string          `xml:"system-err,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitResult  `xml:"failure"`
+	Error     *junitResult  `xml:"error"`
+	Skipped   *junitSkipped `xml:"skipped"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitResult struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// writeJUnit maps the validator to a testsuite and each check to a testcase
+func writeJUnit(r *Report, w io.Writer) error {
+	suite := junitTestSuite{
+		Name:      r.Validator.Name,
+		Tests:     len(r.Checks),
+		Skipped:   r.Count(StatusSkipped),
+		Time:      junitTime(r.DurationSeconds),
+		Timestamp: r.StartedAt.Format(time.RFC3339),
+		Properties: []junitProperty{
+			{Name: "image", Value: r.Image.Name},
+			{Name: "source", Value: r.Validator.Source},
+		},
+		SystemErr: r.Error,
+	}
+	if r.Image.Digest != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "digest", Value: r.Image.Digest})
+	}
+	if r.Validator.Documentation != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "documentation", Value: r.Validator.Documentation})
+	}
+	if r.Container != nil && r.Container.Kept {
+		suite.SystemOut = fmt.Sprintf("Container %s was left running for debugging, remove it with 'docker rm -f %s'\n", r.Container.Name, r.Container.Name)
+	}
+	if r.Diagnostics != nil {
+		suite.SystemOut += "Container diagnostics:\n" + r.Diagnostics.String()
+	}
+
+	for _, check := range r.Checks {
+		testCase := junitTestCase{
+			Name:      check.Description,
+			ClassName: r.Validator.Name,
+			Time:      junitTime(check.DurationSeconds),
+		}
+		if testCase.Name == "" {
+			testCase.Name = check.Name
+		}
+		switch {
+		case (check.Status == StatusFailed || check.Status == StatusError) && !check.blocking():
+			// Only errors fail validation, so other checks are reported as passing
+			message := check.Error
+			if message == "" {
+				message = fmt.Sprintf("check '%s' failed", check.Name)
+			}
+			testCase.SystemOut = fmt.Sprintf("%s: %s\n%s", check.Severity, message, check.Output)
+		case check.Status == StatusFailed:
+			suite.Failures++
+			testCase.Failure = &junitResult{Message: fmt.Sprintf("check '%s' failed", check.Name), Type: string(check.Status), Text: check.Output}
+		case check.Status == StatusError:
+			suite.Errors++
+			testCase.Error = &junitResult{Message: check.Error, Type: string(check.Status), Text: check.Output}
+		case check.Status == StatusSkipped:
+			testCase.Skipped = &junitSkipped{Message: check.skipReason(r)}
+		default:
+			testCase.SystemOut = check.Output
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	suites := junitTestSuites{
+		Name:     "container-canary",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitTime(seconds float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", seconds), "0"), ".")
+}
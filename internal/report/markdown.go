@@ -0,0 +1,135 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Icons for each check outcome, which is the status of the check or its
+// severity when it didn't pass but can't fail validation
+var statusIcons = map[string]string{
+	string(StatusPassed):  "✅",
+	string(StatusFailed):  "❌",
+	string(StatusError):   "⚠️",
+	string(StatusSkipped): "⏭️",
+	"warning":             "🟡",
+	"info":                "ℹ️",
+}
+
+// outcome is the status shown for a check. Checks with warning or info
+// severity which didn't pass show their severity, so that they aren't mistaken
+// for the failures which fail validation.
+func (c Check) outcome() string {
+	if (c.Status == StatusFailed || c.Status == StatusError) && !c.blocking() {
+		return c.Severity
+	}
+	return string(c.Status)
+}
+
+// summary describes how many checks ended with each outcome, e.g. "3 passed, 1 failed, 2 warnings"
+func (r *Report) summary() string {
+	counts := map[string]int{}
+	for _, check := range r.Checks {
+		counts[check.outcome()]++
+	}
+	var parts []string
+	for _, outcome := range []string{string(StatusPassed), string(StatusFailed), string(StatusError), "warning", "info", string(StatusSkipped)} {
+		n := counts[outcome]
+		switch {
+		case n == 0:
+			continue
+		case n > 1 && outcome == "warning":
+			parts = append(parts, fmt.Sprintf("%d warnings", n))
+		default:
+			parts = append(parts, fmt.Sprintf("%d %s", n, outcome))
+		}
+	}
+	if len(parts) == 0 {
+		return "no checks run"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// title is the name of the check as shown in the summary table
+func (c Check) title() string {
+	if c.Description != "" {
+		return c.Description
+	}
+	return c.Name
+}
+
+// writeMarkdown renders a GitHub flavoured summary table, with captured output
+// in collapsible sections beneath it
+func writeMarkdown(r *Report, w io.Writer) error {
+	var b strings.Builder
+	icon := statusIcons[string(StatusPassed)]
+	if !r.Passed {
+		icon = statusIcons[string(StatusFailed)]
+	}
+	name := r.Validator.Name
+	if r.Validator.Documentation != "" {
+		name = fmt.Sprintf("[%s](%s)", name, r.Validator.Documentation)
+	}
+	fmt.Fprintf(&b, "## %s %s\n\n", icon, markdownEscape(r.Image.Name))
+	fmt.Fprintf(&b, "Validated against %s", name)
+	if r.Validator.Description != "" {
+		fmt.Fprintf(&b, " (%s)", markdownEscape(r.Validator.Description))
+	}
+	fmt.Fprintf(&b, ": %s.\n\n", r.summary())
+	if r.Error != "" {
+		fmt.Fprintf(&b, "**Error:** %s\n\n", markdownEscape(r.Error))
+	}
+
+	b.WriteString("| | Check | Status | Duration | Error |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "| %s | %s | %s | %ss | %s |\n",
+			statusIcons[check.outcome()], markdownEscape(check.title()), check.outcome(), junitTime(check.DurationSeconds), markdownEscape(check.Error))
+	}
+
+	for _, check := range r.Checks {
+		if check.Output == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n<details><summary>%s %s</summary>\n\n", statusIcons[check.outcome()], markdownEscape(check.title()))
+		fmt.Fprintf(&b, "```\n%s\n```\n\n</details>\n", strings.TrimRight(strings.ReplaceAll(check.Output, "```", "'''"), "\n"))
+	}
+
+	if r.Diagnostics != nil {
+		b.WriteString("\n<details><summary>🩺 Container diagnostics</summary>\n\n")
+		fmt.Fprintf(&b, "```\n%s```\n\n</details>\n", strings.ReplaceAll(r.Diagnostics.String(), "```", "'''"))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var markdownReplacer = strings.NewReplacer(
+	"|", "\\|",
+	"<", "&lt;",
+	">", "&gt;",
+	"\r\n", "<br>",
+	"\n", "<br>",
+)
+
+// markdownEscape makes text safe to put in a table cell
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(strings.TrimRight(s, "\n"))
+}
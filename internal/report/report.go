@@ -0,0 +1,210 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is bumped whenever a field is removed or changes meaning.
+// New fields may be added without changing it.
+const SchemaVersion = "container-canary.nvidia.com/report/v1"
+
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusError   Status = "error"
+	StatusSkipped Status = "skipped"
+)
+
+// Report is the result of validating an image against a validator
+type Report struct {
+	SchemaVersion string    `json:"schemaVersion"`
+	Validator     Validator `json:"validator"`
+	Image         Image     `json:"image"`
+	Passed        bool      `json:"passed"`
+	// Set when validation could not be completed, for example if the
+	// container failed to start.
+	Error           string    `json:"error,omitempty"`
+	StartedAt       time.Time `json:"startedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	Checks          []Check   `json:"checks"`
+	// The container the checks were run against, if it was started.
+	Container *Container `json:"container,omitempty"`
+	// Collected when a check failed or the container failed to start.
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+}
+
+type Validator struct {
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+	// The path or URL the validator was loaded from, or builtin:NAME for a
+	// validator built into canary.
+	Source string `json:"source"`
+}
+
+type Image struct {
+	Name string `json:"name"`
+	// The ID of the local image, the digest of its config.
+	Id string `json:"id,omitempty"`
+	// The registry digest of the image, if it was pulled from or pushed to a registry.
+	Digest string `json:"digest,omitempty"`
+	// The Dockerfile the image was built from, used to attach findings to source.
+	Dockerfile string `json:"dockerfile,omitempty"`
+}
+
+type Container struct {
+	Name       string `json:"name"`
+	RunCommand string `json:"runCommand"`
+	// The random UID and GID the container ran as, like '1000650000:0', when
+	// the validator runs it as an arbitrary user.
+	User string `json:"user,omitempty"`
+	// Set when the container was left running for debugging.
+	Kept bool `json:"kept,omitempty"`
+}
+
+// Diagnostics describe the container at the end of a failed validation
+type Diagnostics struct {
+	RunCommand string `json:"runCommand,omitempty"`
+	Status     string `json:"status,omitempty"`
+	// Only set once the container has exited.
+	ExitCode  *int   `json:"exitCode,omitempty"`
+	OOMKilled bool   `json:"oomKilled,omitempty"`
+	Health    string `json:"health,omitempty"`
+	Error     string `json:"error,omitempty"`
+	// Changes a writable copy of a container with a read-only root filesystem
+	// made, like 'A /var/cache/app', when the read-only container failed to start.
+	Writes []string `json:"writes,omitempty"`
+	// The end of the container logs.
+	Logs string `json:"logs,omitempty"`
+}
+
+// String formats the diagnostics for people to read
+func (d *Diagnostics) String() string {
+	var b strings.Builder
+	if d.RunCommand != "" {
+		fmt.Fprintf(&b, "Run command: %s\n", d.RunCommand)
+	}
+	if d.Status != "" {
+		fmt.Fprintf(&b, "Status: %s", d.Status)
+		if d.ExitCode != nil {
+			fmt.Fprintf(&b, " (exit code %d)", *d.ExitCode)
+		}
+		if d.OOMKilled {
+			b.WriteString(", killed after running out of memory")
+		}
+		b.WriteString("\n")
+	}
+	if d.Health != "" {
+		fmt.Fprintf(&b, "Health: %s\n", d.Health)
+	}
+	if d.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", d.Error)
+	}
+	if len(d.Writes) > 0 {
+		b.WriteString("Writes by a copy without a read-only root filesystem:\n")
+		for _, write := range d.Writes {
+			fmt.Fprintf(&b, "  %s\n", write)
+		}
+	}
+	if d.Logs != "" {
+		fmt.Fprintf(&b, "Logs:\n%s\n", strings.TrimRight(d.Logs, "\n"))
+	} else {
+		b.WriteString("Logs: (empty)\n")
+	}
+	return b.String()
+}
+
+type Check struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Status      Status `json:"status"`
+	// How much the check not passing matters, error, warning or info. Only
+	// errors fail validation.
+	Severity string `json:"severity,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Why the check was skipped, when it wasn't selected to run.
+	Reason          string  `json:"reason,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	// How many times the probe was run.
+	Attempts int    `json:"attempts"`
+	Output   string `json:"output,omitempty"`
+}
+
+// Count returns the number of checks with a status
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, check := range r.Checks {
+		if check.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// blocking reports whether the check not passing fails validation. Reports
+// written before checks had severities have none, when every check was an error.
+func (c Check) blocking() bool {
+	return c.Severity == "" || c.Severity == "error"
+}
+
+// skipReason explains why a skipped check didn't run, either it wasn't
+// selected or the validation stopped before it could
+func (c Check) skipReason(r *Report) string {
+	if c.Reason != "" {
+		return c.Reason
+	}
+	return r.Error
+}
+
+type writer func(*Report, io.Writer) error
+
+var formats = map[string]writer{
+	"html":     writeHTML,
+	"json":     writeJSON,
+	"junit":    writeJUnit,
+	"markdown": writeMarkdown,
+	"sarif":    writeSARIF,
+	"tap":      writeTAP,
+}
+
+// Formats lists the supported output formats
+func Formats() []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write renders the report in a format
+func (r *Report) Write(format string, w io.Writer) error {
+	write, ok := formats[format]
+	if !ok {
+		return fmt.Errorf("unknown output format '%s'", format)
+	}
+	return write(r, w)
+}
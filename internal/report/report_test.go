@@ -0,0 +1,373 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func exampleReport() *Report {
+	return &Report{
+		SchemaVersion: SchemaVersion,
+		Validator: Validator{
+			Name:          "kubeflow",
+			Description:   "Kubeflow notebooks",
+			Documentation: "https://www.kubeflow.org/docs/components/notebooks/container-images/",
+			Source:        "examples/kubeflow.yaml",
+		},
+		Image:           Image{Name: "container-canary/kubeflow:shouldfail", Id: "sha256:0123456789abcdef"},
+		Passed:          false,
+		StartedAt:       time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC),
+		DurationSeconds: 12.5,
+		Checks: []Check{
+			{Name: "user", Description: "👩 User is jovyan", Status: StatusFailed, DurationSeconds: 0.2, Attempts: 1, Output: "bob\n"},
+			{Name: "uid", Description: "🆔 User ID is 1000", Status: StatusPassed, DurationSeconds: 0.1, Attempts: 1},
+			{Name: "http", Description: "🌏 Exposes an HTTP interface on port 8888", Status: StatusError, Error: "check timed out after 30 seconds", DurationSeconds: 30, Attempts: 30},
+			{Name: "home", Description: "🏠 Home directory is /home/jovyan", Status: StatusSkipped},
+		},
+	}
+}
+
+func TestJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	b := new(bytes.Buffer)
+	err := exampleReport().Write("json", b)
+	assert.Nil(err)
+	assert.Contains(b.String(), `"schemaVersion": "container-canary.nvidia.com/report/v1"`)
+	assert.Contains(b.String(), `"status": "error"`)
+	assert.Contains(b.String(), `"error": "check timed out after 30 seconds"`)
+
+	r, err := ReadJSON(b)
+	assert.Nil(err)
+	assert.Equal(exampleReport(), r)
+	assert.Equal(1, r.Count(StatusSkipped))
+
+	_, err = ReadJSON(strings.NewReader(`{"schemaVersion": "v0"}`))
+	assert.NotNil(err)
+}
+
+func TestUnknownFormat(t *testing.T) {
+	err := exampleReport().Write("yaml", new(bytes.Buffer))
+	assert.NotNil(t, err)
+}
+
+func TestJUnit(t *testing.T) {
+	assert := assert.New(t)
+
+	r := exampleReport()
+	r.Container = &Container{Name: "canary-runner-1234", Kept: true}
+	b := new(bytes.Buffer)
+	err := r.Write("junit", b)
+	assert.Nil(err)
+
+	out := b.String()
+	assert.True(strings.HasPrefix(out, `<?xml version="1.0" encoding="UTF-8"?>`))
+	assert.Contains(out, `<testsuite name="kubeflow" tests="4" failures="1" errors="1" skipped="1" time="12.5" timestamp="2022-03-01T12:00:00Z">`)
+	assert.Contains(out, `<testcase name="👩 User is jovyan" classname="kubeflow" time="0.2">`)
+	assert.Contains(out, `<failure message="check &#39;user&#39; failed" type="failed">bob&#xA;</failure>`)
+	assert.Contains(out, `<error message="check timed out after 30 seconds" type="error"></error>`)
+	assert.Contains(out, `<skipped></skipped>`)
+	assert.Contains(out, `Container canary-runner-1234 was left running for debugging`)
+}
+
+func TestSARIF(t *testing.T) {
+	assert := assert.New(t)
+
+	r := exampleReport()
+	r.Image.Dockerfile = "docker/Dockerfile"
+	b := new(bytes.Buffer)
+	err := r.Write("sarif", b)
+	assert.Nil(err)
+
+	var log sarifLog
+	err = json.Unmarshal(b.Bytes(), &log)
+	assert.Nil(err)
+	assert.Equal("2.1.0", log.Version)
+	run := log.Runs[0]
+	assert.Len(run.Tool.Driver.Rules, 4)
+	assert.Equal("kubeflow/user", run.Tool.Driver.Rules[0].ID)
+	assert.Equal("👩 User is jovyan", run.Tool.Driver.Rules[0].ShortDescription.Text)
+	assert.Equal(r.Validator.Documentation, run.Tool.Driver.Rules[0].HelpURI)
+	assert.True(run.Invocations[0].ExecutionSuccessful)
+
+	// Only failed and errored checks are results
+	assert.Len(run.Results, 2)
+	assert.Equal("kubeflow/user", run.Results[0].RuleID)
+	assert.Equal("kubeflow/http", run.Results[1].RuleID)
+	assert.Equal(2, run.Results[1].RuleIndex)
+	assert.Contains(run.Results[1].Message.Text, "check timed out after 30 seconds")
+	assert.Equal("docker/Dockerfile", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+}
+
+func TestSARIFRuleIDs(t *testing.T) {
+	seen := map[string]bool{}
+	assert.Equal(t, "databricks/sudo", sarifRuleID("databricks", "sudo", 1, seen))
+	assert.Equal(t, "databricks/sudo#3", sarifRuleID("databricks", "sudo", 2, seen))
+	assert.Equal(t, "databricks/#4", sarifRuleID("databricks", "", 3, seen))
+	// Names which are numbers or look like a position can't clash either
+	assert.Equal(t, "databricks/3", sarifRuleID("databricks", "3", 4, seen))
+	assert.Equal(t, "databricks/sudo#3#7", sarifRuleID("databricks", "sudo#3", 6, seen))
+}
+
+func TestMarkdown(t *testing.T) {
+	assert := assert.New(t)
+
+	r := exampleReport()
+	r.Checks[0].Output = "bob | alice\n"
+	b := new(bytes.Buffer)
+	err := r.Write("markdown", b)
+	assert.Nil(err)
+
+	out := b.String()
+	assert.True(strings.HasPrefix(out, "## ❌ container-canary/kubeflow:shouldfail\n"))
+	assert.Contains(out, "Validated against [kubeflow](https://www.kubeflow.org/docs/components/notebooks/container-images/) (Kubeflow notebooks): 1 passed, 1 failed, 1 error, 1 skipped.")
+	assert.Contains(out, "| ❌ | 👩 User is jovyan | failed | 0.2s |  |\n")
+	assert.Contains(out, "| ⚠️ | 🌏 Exposes an HTTP interface on port 8888 | error | 30s | check timed out after 30 seconds |\n")
+	assert.Contains(out, "<details><summary>❌ 👩 User is jovyan</summary>\n\n```\nbob | alice\n```\n\n</details>\n")
+
+	// Checks which can't fail validation are counted as warnings, not failures
+	r.Passed = true
+	r.Checks[0].Severity = "warning"
+	r.Checks[2].Severity = "info"
+	b.Reset()
+	assert.Nil(r.Write("markdown", b))
+	out = b.String()
+	assert.True(strings.HasPrefix(out, "## ✅ container-canary/kubeflow:shouldfail\n"))
+	assert.Contains(out, ": 1 passed, 1 warning, 1 info, 1 skipped.")
+	assert.Contains(out, "| 🟡 | 👩 User is jovyan | warning | 0.2s |  |\n")
+	assert.Contains(out, "| ℹ️ | 🌏 Exposes an HTTP interface on port 8888 | info | 30s | check timed out after 30 seconds |\n")
+	assert.NotContains(out, "failed")
+}
+
+func TestHTML(t *testing.T) {
+	assert := assert.New(t)
+
+	r := exampleReport()
+	r.Checks[0].Output = "<script>alert(1)</script>"
+	b := new(bytes.Buffer)
+	err := r.Write("html", b)
+	assert.Nil(err)
+
+	out := b.String()
+	assert.True(strings.HasPrefix(out, "<!DOCTYPE html>"))
+	assert.Contains(out, `<a href="https://www.kubeflow.org/docs/components/notebooks/container-images/">kubeflow</a>`)
+	assert.Contains(out, `<tr class="failed">`)
+	assert.Contains(out, "<details><summary>Output</summary><pre>&lt;script&gt;alert(1)&lt;/script&gt;</pre></details>")
+	assert.Contains(out, "<td>check timed out after 30 seconds</td>")
+	assert.NotContains(out, "<script>")
+
+	r = exampleReport()
+	r.Passed = true
+	r.Checks[0].Severity = "warning"
+	r.Checks[2].Severity = "warning"
+	b.Reset()
+	assert.Nil(r.Write("html", b))
+	out = b.String()
+	assert.Contains(out, "<h1>✅ ")
+	assert.Contains(out, ": 1 passed, 2 warnings, 1 skipped.</p>")
+	assert.Contains(out, "<tr class=\"warning\">\n<td>🟡</td>")
+	assert.Contains(out, "<td>warning</td>")
+	assert.NotContains(out, `<tr class="failed">`)
+}
+
+func TestTAP(t *testing.T) {
+	assert := assert.New(t)
+
+	b := new(bytes.Buffer)
+	err := exampleReport().Write("tap", b)
+	assert.Nil(err)
+
+	out := b.String()
+	assert.True(strings.HasPrefix(out, "TAP version 13\n1..4\n"))
+	assert.Contains(out, "not ok 1 - 👩 User is jovyan\n  ---\n  message: check 'user' failed\n  severity: failed\n")
+	assert.Contains(out, "  output: |\n    bob\n  ...\n")
+	assert.Contains(out, "ok 2 - 🆔 User ID is 1000\n")
+	assert.Contains(out, "not ok 3 - 🌏 Exposes an HTTP interface on port 8888\n  ---\n  message: check timed out after 30 seconds\n  severity: error\n")
+	// Checks which didn't run because validation stopped must not pass
+	assert.Contains(out, "not ok 4 - 🏠 Home directory is /home/jovyan\n  ---\n  message: validation stopped before the check ran\n  severity: skipped\n")
+	assert.NotContains(out, "Bail out!")
+
+	r := exampleReport()
+	r.Checks = []Check{}
+	r.Error = "no checks found"
+	b.Reset()
+	err = r.Write("tap", b)
+	assert.Nil(err)
+	assert.Equal("TAP version 13\n1..0\nBail out! no checks found\n", b.String())
+
+	r = exampleReport()
+	r.Error = "container failed to start, it exited with code 1"
+	b.Reset()
+	err = r.Write("tap", b)
+	assert.Nil(err)
+	assert.True(strings.HasSuffix(b.String(), "Bail out! container failed to start, it exited with code 1\n"))
+
+	r = exampleReport()
+	r.Checks[3].Reason = "by --skip slow"
+	b.Reset()
+	err = r.Write("tap", b)
+	assert.Nil(err)
+	assert.Contains(b.String(), "ok 4 - 🏠 Home directory is /home/jovyan # SKIP by --skip slow\n")
+}
+
+func TestDiagnostics(t *testing.T) {
+	assert := assert.New(t)
+
+	exitCode := 137
+	r := exampleReport()
+	r.Diagnostics = &Diagnostics{
+		RunCommand: "docker run -d container-canary/kubeflow:shouldfail",
+		Status:     "exited",
+		ExitCode:   &exitCode,
+		OOMKilled:  true,
+		Writes:     []string{"C /var", "A /var/cache/app"},
+		Logs:       "Killed\n",
+	}
+	assert.Equal(`Run command: docker run -d container-canary/kubeflow:shouldfail
+Status: exited (exit code 137), killed after running out of memory
+Writes by a copy without a read-only root filesystem:
+  C /var
+  A /var/cache/app
+Logs:
+Killed
+`, r.Diagnostics.String())
+
+	for _, format := range Formats() {
+		b := new(bytes.Buffer)
+		err := r.Write(format, b)
+		assert.Nil(err)
+		assert.Contains(b.String(), "docker run -d container-canary/kubeflow:shouldfail", format)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	assert := assert.New(t)
+
+	old := exampleReport()
+	old.Image.Name = "container-canary/kubeflow:1.0"
+	current := exampleReport()
+	current.Image.Name = "container-canary/kubeflow:1.1"
+	current.Checks[0].Status = StatusPassed // user fixed
+	current.Checks[1].Status = StatusFailed // uid regressed
+	current.Checks = append(current.Checks[:3], Check{Name: "sudo", Description: "🔒 Can't sudo", Status: StatusFailed})
+
+	c := Compare(old, current)
+	assert.Len(c.Checks, 5)
+	assert.Equal(ChangeFixed, c.Checks[0].Change)
+	assert.Equal(ChangeRegressed, c.Checks[1].Change)
+	assert.Equal(ChangeUnchanged, c.Checks[2].Change)
+	assert.Equal(ChangeNew, c.Checks[3].Change)
+	assert.Equal(ChangeRemoved, c.Checks[4].Change)
+	assert.Equal("home", c.Checks[4].Name)
+	assert.Equal(1, c.Count(ChangeRegressed))
+
+	b := new(bytes.Buffer)
+	assert.Nil(c.Write(b))
+	out := b.String()
+	assert.True(strings.HasPrefix(out, "Comparing container-canary/kubeflow:1.0 with container-canary/kubeflow:1.1 against kubeflow\n"))
+	assert.Contains(out, " regressed  🆔 User ID is 1000")
+	assert.Contains(out, "[passed -> failed]\n")
+	assert.Contains(out, "[error]\n")
+	assert.True(strings.HasSuffix(out, "1 regressed, 1 fixed, 1 unchanged, 1 new, 1 removed\n"))
+}
+
+func TestCompareFailedRun(t *testing.T) {
+	assert := assert.New(t)
+
+	// A manifest which fails to load leaves a report with an error and no checks
+	current := exampleReport()
+	current.Checks = []Check{}
+	current.Error = "no such file examples/kubeflow.yaml"
+	c := Compare(exampleReport(), current)
+	assert.Equal(1, c.Count(ChangeRegressed))
+	assert.Equal(3, c.Count(ChangeRemoved))
+	assert.Equal("uid", c.Checks[1].Name)
+	assert.Equal(ChangeRegressed, c.Checks[1].Change)
+
+	b := new(bytes.Buffer)
+	assert.Nil(c.Write(b))
+	assert.Contains(b.String(), "did not finish: no such file examples/kubeflow.yaml\n")
+	assert.Contains(b.String(), "[passed -> missing]\n")
+
+	// Checks which only warn can go missing without regressing
+	old := exampleReport()
+	old.Checks[1].Severity = "warning"
+	c = Compare(old, current)
+	assert.Equal(0, c.Count(ChangeRegressed))
+}
+
+func TestCompareDeselected(t *testing.T) {
+	assert := assert.New(t)
+
+	// Checks left out with --only or --skip weren't compared, so haven't regressed
+	current := exampleReport()
+	current.Checks[1].Status = StatusSkipped
+	current.Checks[1].Reason = "by --skip uid"
+	c := Compare(exampleReport(), current)
+	assert.Equal(ChangeUnchanged, c.Checks[1].Change)
+	assert.Equal(0, c.Count(ChangeRegressed))
+
+	// Nor have they been fixed when they are run again
+	c = Compare(current, exampleReport())
+	assert.Equal(ChangeUnchanged, c.Checks[1].Change)
+
+	// Checks skipped because validation failed still regress
+	current.Checks[1].Reason = ""
+	c = Compare(exampleReport(), current)
+	assert.Equal(ChangeRegressed, c.Checks[1].Change)
+}
+
+func TestSeverity(t *testing.T) {
+	assert := assert.New(t)
+
+	r := exampleReport()
+	r.Checks[0].Severity = "warning"
+	r.Checks[2].Severity = "info"
+
+	b := new(bytes.Buffer)
+	assert.Nil(r.Write("sarif", b))
+	var log sarifLog
+	assert.Nil(json.Unmarshal(b.Bytes(), &log))
+	assert.Equal("warning", log.Runs[0].Results[0].Level)
+	assert.Equal("note", log.Runs[0].Results[1].Level)
+
+	// Only errors fail, so warnings and info are passing test cases
+	b.Reset()
+	assert.Nil(r.Write("junit", b))
+	assert.Contains(b.String(), `failures="0" errors="0"`)
+	assert.Contains(b.String(), "<system-out>warning: check &#39;user&#39; failed&#xA;bob&#xA;</system-out>")
+
+	b.Reset()
+	assert.Nil(r.Write("tap", b))
+	assert.Contains(b.String(), "not ok 1 - 👩 User is jovyan # TODO warning\n")
+	assert.Contains(b.String(), "not ok 3 - 🌏 Exposes an HTTP interface on port 8888 # TODO info\n")
+
+	current := exampleReport()
+	current.Checks[1].Status = StatusFailed
+	current.Checks[1].Severity = "warning"
+	c := Compare(exampleReport(), current)
+	assert.Equal(ChangeUnchanged, c.Checks[1].Change)
+	assert.Equal(0, c.Count(ChangeRegressed))
+}
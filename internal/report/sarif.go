@@ -0,0 +1,196 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/nvidia/container-canary/internal"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Version        string      `json:"version"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name,omitempty"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// writeSARIF makes each check a rule and each failing check a result
+func writeSARIF(r *Report, w io.Writer) error {
+	driver := sarifDriver{
+		Name:           "container-canary",
+		InformationURI: "https://github.com/NVIDIA/container-canary",
+		Version:        internal.Version,
+		Rules:          []sarifRule{},
+	}
+	run := sarifRun{
+		Invocations: []sarifInvocation{{ExecutionSuccessful: r.Error == ""}},
+		Results:     []sarifResult{},
+	}
+	if r.Error != "" {
+		run.Invocations[0].ToolExecutionNotifications = []sarifNotification{{Level: "error", Message: sarifMessage{Text: r.Error}}}
+	}
+	if r.Diagnostics != nil {
+		run.Invocations[0].ToolExecutionNotifications = append(run.Invocations[0].ToolExecutionNotifications,
+			sarifNotification{Level: "note", Message: sarifMessage{Text: "Container diagnostics:\n" + r.Diagnostics.String()}})
+	}
+
+	var locations []sarifLocation
+	if r.Image.Dockerfile != "" {
+		locations = []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(r.Image.Dockerfile), URIBaseID: "%SRCROOT%"},
+				Region:           sarifRegion{StartLine: 1},
+			},
+		}}
+	}
+
+	ids := map[string]bool{}
+	for i, check := range r.Checks {
+		id := sarifRuleID(r.Validator.Name, check.Name, i, ids)
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:               id,
+			Name:             check.Name,
+			ShortDescription: sarifMessage{Text: check.Description},
+			HelpURI:          r.Validator.Documentation,
+		})
+		if check.Status != StatusFailed && check.Status != StatusError {
+			continue
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    id,
+			RuleIndex: i,
+			Level:     sarifLevel(check),
+			Message:   sarifMessage{Text: sarifResultMessage(r, check)},
+			Locations: locations,
+		})
+	}
+	run.Tool = sarifTool{Driver: driver}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
+
+// sarifLevel maps the severity of a check to a SARIF result level
+func sarifLevel(check Check) string {
+	switch check.Severity {
+	case "warning":
+		return "warning"
+	case "info":
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+// sarifRuleID namespaces the check name by validator. Checks without a unique
+// name add their position to it, as 'validator/name#3', which is repeated in
+// the unlikely case that a check is really called that.
+func sarifRuleID(validator string, name string, index int, seen map[string]bool) string {
+	id := fmt.Sprintf("%s/%s", validator, name)
+	if name == "" || seen[id] {
+		id = fmt.Sprintf("%s#%d", id, index+1)
+	}
+	for seen[id] {
+		id = fmt.Sprintf("%s#%d", id, index+1)
+	}
+	seen[id] = true
+	return id
+}
+
+func sarifResultMessage(r *Report, check Check) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s does not meet the %s requirement: %s", r.Image.Name, r.Validator.Name, check.Description)
+	if check.Error != "" {
+		fmt.Fprintf(&b, " (%s)", check.Error)
+	}
+	if check.Output != "" {
+		fmt.Fprintf(&b, "\n\n%s", check.Output)
+	}
+	return b.String()
+}
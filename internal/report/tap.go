@@ -0,0 +1,98 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// tapDiagnostic is the YAML block written beneath a check which did not pass
+type tapDiagnostic struct {
+	Message  string  `yaml:"message"`
+	Severity string  `yaml:"severity"`
+	Name     string  `yaml:"name"`
+	Duration float64 `yaml:"duration_ms"`
+	Attempts int     `yaml:"attempts"`
+	Output   string  `yaml:"output,omitempty"`
+}
+
+var tapEscaper = strings.NewReplacer("\\", "\\\\", "#", "\\#", "\n", " ")
+
+// writeTAP writes a TAP version 13 stream with one test point per check. Only
+// checks deselected with --only or --skip are skipped, those which didn't run
+// because validation stopped are failures, and the stream bails out with the
+// error so that a run which couldn't finish never passes.
+func writeTAP(r *Report, w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("TAP version 13\n")
+	fmt.Fprintf(&b, "1..%d\n", len(r.Checks))
+	for i, check := range r.Checks {
+		description := tapEscaper.Replace(check.title())
+		switch {
+		case check.Status == StatusPassed:
+			fmt.Fprintf(&b, "ok %d - %s\n", i+1, description)
+		case check.deselected():
+			fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", i+1, description, tapEscaper.Replace(check.Reason))
+		default:
+			// TODO marks a test point whose failure doesn't fail the run
+			if check.blocking() {
+				fmt.Fprintf(&b, "not ok %d - %s\n", i+1, description)
+			} else {
+				fmt.Fprintf(&b, "not ok %d - %s # TODO %s\n", i+1, description, check.Severity)
+			}
+			diagnostic := tapDiagnostic{
+				Message:  check.Error,
+				Severity: string(check.Status),
+				Name:     check.Name,
+				Duration: check.DurationSeconds * 1000,
+				Attempts: check.Attempts,
+				Output:   check.Output,
+			}
+			if check.Status == StatusSkipped {
+				diagnostic.Message = "validation stopped before the check ran"
+			}
+			if diagnostic.Message == "" {
+				diagnostic.Message = fmt.Sprintf("check '%s' failed", check.Name)
+			}
+			out, err := yaml.Marshal(diagnostic)
+			if err != nil {
+				return err
+			}
+			b.WriteString("  ---\n")
+			for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+				fmt.Fprintf(&b, "  %s\n", line)
+			}
+			b.WriteString("  ...\n")
+		}
+	}
+	if r.Diagnostics != nil {
+		b.WriteString("# Container diagnostics\n")
+		for _, line := range strings.Split(strings.TrimRight(r.Diagnostics.String(), "\n"), "\n") {
+			fmt.Fprintf(&b, "#   %s\n", line)
+		}
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&b, "Bail out! %s\n", strings.ReplaceAll(r.Error, "\n", " "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
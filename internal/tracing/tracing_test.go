@@ -0,0 +1,65 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetupTraceFile(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	shutdown, err := Setup(context.Background(), path)
+	assert.Nil(err)
+
+	_, span := Tracer("test").Start(context.Background(), "loadConfig")
+	span.End()
+	assert.Nil(shutdown(context.Background()))
+
+	b, err := os.ReadFile(path)
+	assert.Nil(err)
+	assert.Contains(string(b), `"Name":"loadConfig"`)
+	assert.Contains(string(b), `"Value":"container-canary"`)
+}
+
+func TestSetupDisabled(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	previous := otel.GetTracerProvider()
+
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	shutdown, err := Setup(context.Background(), path)
+	assert.Nil(err)
+	assert.Nil(shutdown(context.Background()))
+	assert.Equal(previous, otel.GetTracerProvider())
+	_, err = os.Stat(path)
+	assert.True(os.IsNotExist(err))
+}
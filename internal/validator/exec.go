@@ -22,11 +22,11 @@ import (
 	"github.com/nvidia/container-canary/internal/container"
 )
 
-func ExecCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool, error) {
+func ExecCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool, string, error) {
 	action := probe.Exec
-	_, err := c.Exec(action.Command...)
+	out, err := c.Exec(action.Command...)
 	if err != nil {
-		return false, nil
+		return false, out, nil
 	}
-	return true, nil
+	return true, out, nil
 }
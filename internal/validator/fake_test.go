@@ -0,0 +1,76 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nvidia/container-canary/internal/container"
+)
+
+// fakeContainer is an in-memory container for testing probes without Docker
+type fakeContainer struct {
+	info      container.ContainerInfo
+	image     container.ImageInfo
+	history   []container.ImageLayer
+	files     []container.FileInfo
+	processes []container.Process
+	changes   []container.FileChange
+	logs      string
+	// Output of exec'd commands keyed by the space separated command line,
+	// commands which are missing fail.
+	exec map[string]string
+	// Error returned by Start, and whether Remove has been called
+	startErr error
+	removed  bool
+}
+
+func (f *fakeContainer) Start(timeoutSeconds int) error { return f.startErr }
+
+func (f *fakeContainer) Remove() error {
+	f.removed = true
+	return nil
+}
+
+func (f *fakeContainer) Status() (*container.ContainerInfo, error) { return &f.info, nil }
+
+func (f *fakeContainer) Exec(command ...string) (string, error) {
+	if out, ok := f.exec[strings.Join(command, " ")]; ok {
+		return out, nil
+	}
+	return "", errors.New("exit status 127")
+}
+
+func (f *fakeContainer) Logs() (string, error) { return f.logs, nil }
+
+func (f *fakeContainer) InspectImage() (*container.ImageInfo, error) { return &f.image, nil }
+
+func (f *fakeContainer) ImageHistory() ([]container.ImageLayer, error) { return f.history, nil }
+
+func (f *fakeContainer) CompressedImageSize() (int64, error) { return f.image.Size / 2, nil }
+
+func (f *fakeContainer) ReadFiles(path string) (map[string][]byte, error) {
+	return nil, errors.New("no such file")
+}
+
+func (f *fakeContainer) Files() ([]container.FileInfo, error) { return f.files, nil }
+
+func (f *fakeContainer) Processes() ([]container.Process, error) { return f.processes, nil }
+
+func (f *fakeContainer) Diff() ([]container.FileChange, error) { return f.changes, nil }
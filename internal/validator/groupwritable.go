@@ -0,0 +1,80 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+)
+
+// Docker falls back to / as the home directory of users without a passwd entry
+const defaultHome = "/"
+
+func GroupWritableCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool, string, error) {
+	action := probe.GroupWritable
+	home, err := homeDirectory(c)
+	if err != nil {
+		return false, "", err
+	}
+	files, err := c.Files()
+	if err != nil {
+		return false, "", err
+	}
+	modes := map[string]container.FileInfo{}
+	for _, f := range files {
+		modes[f.Path] = f
+	}
+
+	var b strings.Builder
+	passed := true
+	for _, p := range append([]string{home}, action.Paths...) {
+		p = path.Clean(p)
+		f, ok := modes[p]
+		switch {
+		case !ok:
+			passed = false
+			fmt.Fprintf(&b, "%s: does not exist\n", p)
+		case f.Mode.Perm()&0o002 != 0 || (f.Gid == 0 && f.Mode.Perm()&0o020 != 0):
+			fmt.Fprintf(&b, "%s: writable (GID %d, mode %#o)\n", p, f.Gid, f.Mode.Perm())
+		default:
+			passed = false
+			fmt.Fprintf(&b, "%s: not writable by GID 0 (GID %d, mode %#o)\n", p, f.Gid, f.Mode.Perm())
+		}
+	}
+	return passed, b.String(), nil
+}
+
+func homeDirectory(c container.ContainerInterface) (string, error) {
+	if out, err := c.Exec("printenv", "HOME"); err == nil && strings.TrimSpace(out) != "" {
+		return strings.TrimSpace(out), nil
+	}
+	info, err := c.Status()
+	if err != nil {
+		return "", err
+	}
+	for _, env := range info.Config.Env {
+		if home, ok := strings.CutPrefix(env, "HOME="); ok {
+			return home, nil
+		}
+	}
+	return defaultHome, nil
+}
@@ -0,0 +1,74 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"os"
+	"testing"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupWritableCheck(t *testing.T) {
+	assert := assert.New(t)
+	c := &fakeContainer{
+		exec: map[string]string{"printenv HOME": "/home/app\n"},
+		files: []container.FileInfo{
+			{Path: "/", Mode: 0o755 | os.ModeDir},
+			{Path: "/home/app", Mode: 0o775 | os.ModeDir, Gid: 0},
+			{Path: "/opt/data", Mode: 0o775 | os.ModeDir, Gid: 1000},
+		},
+	}
+
+	passed, out, err := GroupWritableCheck(c, &canaryv1.Probe{GroupWritable: &canaryv1.GroupWritableAction{}})
+	assert.Nil(err)
+	assert.True(passed)
+	assert.Equal("/home/app: writable (GID 0, mode 0775)\n", out)
+
+	passed, out, err = GroupWritableCheck(c, &canaryv1.Probe{GroupWritable: &canaryv1.GroupWritableAction{Paths: []string{"/opt/data", "/srv"}}})
+	assert.Nil(err)
+	assert.False(passed)
+	assert.Contains(out, "/opt/data: not writable by GID 0 (GID 1000, mode 0775)\n")
+	assert.Contains(out, "/srv: does not exist\n")
+
+	// Without a HOME variable Docker uses /, which is rarely writable
+	c.exec = nil
+	passed, out, err = GroupWritableCheck(c, &canaryv1.Probe{GroupWritable: &canaryv1.GroupWritableAction{}})
+	assert.Nil(err)
+	assert.False(passed)
+	assert.Equal("/: not writable by GID 0 (GID 0, mode 0755)\n", out)
+}
+
+func TestApplyArbitraryUser(t *testing.T) {
+	assert := assert.New(t)
+
+	v := &canaryv1.Validator{}
+	applyArbitraryUser(v, false)
+	assert.Nil(v.SecurityContext)
+	assert.Empty(v.Checks)
+
+	applyArbitraryUser(v, true)
+	assert.True(v.SecurityContext.RunAsArbitraryUser)
+	assert.Len(v.Checks, 1)
+	assert.NotNil(v.Checks[0].Probe.GroupWritable)
+
+	applyArbitraryUser(v, true)
+	assert.Len(v.Checks, 1, "should not add a second check")
+}
@@ -26,12 +26,12 @@ import (
 	"github.com/nvidia/container-canary/internal/container"
 )
 
-func HTTPGetCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool, error) {
+func HTTPGetCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool, string, error) {
 	action := probe.HTTPGet
 	client := &http.Client{}
 	req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d%s", action.Port, action.Path), nil)
 	if err != nil {
-		return false, nil
+		return false, "", nil
 	}
 	req.Close = true
 
@@ -40,15 +40,15 @@ func HTTPGetCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool,
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return false, nil
+		return false, "", nil
 	}
 	for _, header := range action.ResponseHTTPHeaders {
 		if val, ok := resp.Header[header.Name]; ok {
 			if header.Value != strings.Join(val[:], "") {
-				return false, nil
+				return false, "", nil
 			}
 		}
 	}
 	defer resp.Body.Close()
-	return true, nil
+	return true, "", nil
 }
@@ -18,6 +18,8 @@
 package validator
 
 import (
+	"context"
+	"fmt"
 	"os/exec"
 
 	"github.com/spf13/cobra"
@@ -35,3 +37,20 @@ func CheckImage(cmd *cobra.Command, image string, runtime string) bool {
 		return command.Run() == nil
 	}
 }
+
+// imageAvailable finds the image locally or pulls it, tests replace it to run without Docker
+var imageAvailable = func(cmd *cobra.Command, image string) bool {
+	return CheckImage(cmd, image, "docker")
+}
+
+// pullImage makes sure the image is available locally, pulling it if it
+// isn't, in a span of its own as pulling can take longer than validating
+func pullImage(ctx context.Context, cmd *cobra.Command, image string) error {
+	_, span := tracer.Start(ctx, "pullImage")
+	var err error
+	if !imageAvailable(cmd, image) {
+		err = fmt.Errorf("no such image: %s", image)
+	}
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,100 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+)
+
+// Number of layers to list when an image size check fails
+const largestLayersShown = 5
+
+func ImageSizeCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool, string, error) {
+	action := probe.ImageSize
+	info, err := c.InspectImage()
+	if err != nil {
+		return false, "", fmt.Errorf("failed to inspect image: %w", err)
+	}
+	history, err := c.ImageHistory()
+	if err != nil {
+		return false, "", fmt.Errorf("failed to read image history: %w", err)
+	}
+
+	var problems []string
+	if action.MaxSize > 0 && canaryv1.ByteSize(info.Size) > action.MaxSize {
+		problems = append(problems, fmt.Sprintf("image size %s exceeds %s", canaryv1.ByteSize(info.Size), action.MaxSize))
+	}
+	if action.MaxCompressedSize > 0 {
+		compressed, err := c.CompressedImageSize()
+		if err != nil {
+			return false, "", fmt.Errorf("failed to measure compressed image size: %w", err)
+		}
+		if canaryv1.ByteSize(compressed) > action.MaxCompressedSize {
+			problems = append(problems, fmt.Sprintf("compressed image size %s exceeds %s", canaryv1.ByteSize(compressed), action.MaxCompressedSize))
+		}
+	}
+	if action.MaxLayers > 0 && len(info.RootFS.Layers) > action.MaxLayers {
+		problems = append(problems, fmt.Sprintf("image has %d layers, more than %d", len(info.RootFS.Layers), action.MaxLayers))
+	}
+
+	largest := largestLayers(history)
+	if action.MaxLayerSize > 0 && len(largest) > 0 && canaryv1.ByteSize(largest[0].Size) > action.MaxLayerSize {
+		problems = append(problems, fmt.Sprintf("largest layer is %s, more than %s", canaryv1.ByteSize(largest[0].Size), action.MaxLayerSize))
+	}
+
+	if len(problems) == 0 {
+		return true, "", nil
+	}
+	return false, formatLayerReport(problems, largest), nil
+}
+
+// largestLayers returns the history entries which produced a filesystem layer, biggest first
+func largestLayers(history []container.ImageLayer) []container.ImageLayer {
+	var layers []container.ImageLayer
+	for _, layer := range history {
+		if layer.Size > 0 {
+			layers = append(layers, layer)
+		}
+	}
+	sort.SliceStable(layers, func(i, j int) bool {
+		return layers[i].Size > layers[j].Size
+	})
+	return layers
+}
+
+func formatLayerReport(problems []string, largest []container.ImageLayer) string {
+	var b strings.Builder
+	for _, problem := range problems {
+		fmt.Fprintln(&b, problem)
+	}
+	if len(largest) > largestLayersShown {
+		largest = largest[:largestLayersShown]
+	}
+	if len(largest) > 0 {
+		fmt.Fprintln(&b, "largest layers:")
+	}
+	for _, layer := range largest {
+		fmt.Fprintf(&b, "  %10s  %s\n", canaryv1.ByteSize(layer.Size), strings.TrimSpace(layer.CreatedBy))
+	}
+	return b.String()
+}
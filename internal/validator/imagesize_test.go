@@ -0,0 +1,95 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"testing"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageSizeCheck(t *testing.T) {
+	c := &fakeContainer{
+		image: container.ImageInfo{
+			Size:   3 << 30,
+			RootFS: container.ImageRootFS{Layers: []string{"sha256:a", "sha256:b", "sha256:c"}},
+		},
+		history: []container.ImageLayer{
+			{CreatedBy: "CMD [\"python\"]", Size: 0},
+			{CreatedBy: "RUN pip install torch", Size: 2 << 30},
+			{CreatedBy: "COPY . /app ", Size: 512 << 20},
+			{CreatedBy: "/bin/sh -c #(nop) ADD file:rootfs in /", Size: 512 << 20},
+		},
+	}
+	layers := `largest layers:
+      2.0GiB  RUN pip install torch
+    512.0MiB  COPY . /app
+    512.0MiB  /bin/sh -c #(nop) ADD file:rootfs in /
+`
+
+	tests := []struct {
+		name   string
+		action canaryv1.ImageSizeAction
+		passed bool
+		output string
+	}{
+		{"no limits", canaryv1.ImageSizeAction{}, true, ""},
+		{"within limits", canaryv1.ImageSizeAction{MaxSize: 4 << 30, MaxCompressedSize: 2 << 30, MaxLayers: 3, MaxLayerSize: 2 << 30}, true, ""},
+		{"size", canaryv1.ImageSizeAction{MaxSize: 2 << 30}, false, "image size 3.0GiB exceeds 2.0GiB\n" + layers},
+		{"compressed size", canaryv1.ImageSizeAction{MaxCompressedSize: 1 << 30}, false, "compressed image size 1.5GiB exceeds 1.0GiB\n" + layers},
+		{"layers", canaryv1.ImageSizeAction{MaxLayers: 2}, false, "image has 3 layers, more than 2\n" + layers},
+		{"layer size", canaryv1.ImageSizeAction{MaxLayerSize: 1 << 30}, false, "largest layer is 2.0GiB, more than 1.0GiB\n" + layers},
+		{
+			"everything",
+			canaryv1.ImageSizeAction{MaxSize: 1 << 30, MaxCompressedSize: 1 << 30, MaxLayers: 1, MaxLayerSize: 1 << 30},
+			false,
+			"image size 3.0GiB exceeds 1.0GiB\ncompressed image size 1.5GiB exceeds 1.0GiB\nimage has 3 layers, more than 1\nlargest layer is 2.0GiB, more than 1.0GiB\n" + layers,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			action := test.action
+			passed, out, err := ImageSizeCheck(c, &canaryv1.Probe{ImageSize: &action})
+			assert.Nil(err)
+			assert.Equal(test.passed, passed)
+			assert.Equal(test.output, out)
+		})
+	}
+}
+
+func TestFormatLayerReport(t *testing.T) {
+	assert := assert.New(t)
+	var history []container.ImageLayer
+	for i := 1; i <= 7; i++ {
+		history = append(history, container.ImageLayer{CreatedBy: "RUN step", Size: int64(i) << 20})
+	}
+
+	out := formatLayerReport([]string{"image size 28.0MiB exceeds 10.0MiB"}, largestLayers(history))
+	assert.Equal(`image size 28.0MiB exceeds 10.0MiB
+largest layers:
+      7.0MiB  RUN step
+      6.0MiB  RUN step
+      5.0MiB  RUN step
+      4.0MiB  RUN step
+      3.0MiB  RUN step
+`, out)
+	assert.Equal("image size 1B exceeds 0B\n", formatLayerReport([]string{"image size 1B exceeds 0B"}, nil))
+}
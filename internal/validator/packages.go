@@ -0,0 +1,509 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+)
+
+type installedPackage struct {
+	Name    string
+	Version string
+}
+
+type packageManager struct {
+	Name string
+	// Command listing installed packages, run without a shell.
+	Command []string
+	Parse   func(string) []installedPackage
+	// Database files read straight from the filesystem when Command is unavailable.
+	Database      []string
+	ParseDatabase func(map[string][]byte) []installedPackage
+	// Packages found from the paths in the container filesystem when there is no
+	// Command or Database, as the filesystem is exported to list them.
+	ParseFiles func([]container.FileInfo) []installedPackage
+	// Whether package names are compared case insensitively, treating '-', '_'
+	// and '.' the same, as Python packaging does.
+	NormalizeNames bool
+}
+
+var packageManagers = []packageManager{
+	{
+		Name:          "dpkg",
+		Command:       []string{"dpkg-query", "-W", "-f=${Package}\t${Version}\t${db:Status-Abbrev}\n"},
+		Parse:         parseDpkgQuery,
+		Database:      []string{"/var/lib/dpkg/status", "/var/lib/dpkg/status.d"},
+		ParseDatabase: parseDpkgDatabase,
+	},
+	{
+		Name:          "apk",
+		Command:       []string{"apk", "info", "-v"},
+		Parse:         parseApkInfo,
+		Database:      []string{"/lib/apk/db/installed"},
+		ParseDatabase: parseApkDatabase,
+	},
+	{
+		// The rpm database is a Berkeley DB, ndb or SQLite file of binary rpm
+		// headers depending on the distribution, so unlike the others it can't
+		// be read without the rpm binary.
+		Name:    "rpm",
+		Command: []string{"rpm", "-qa", "--qf", "%{NAME}\t%{VERSION}-%{RELEASE}\n"},
+		Parse:   parseTabSeparated,
+	},
+	{
+		Name:           "pip",
+		Command:        []string{"python3", "-m", "pip", "list", "--disable-pip-version-check", "--format=freeze"},
+		Parse:          parsePipFreeze,
+		ParseFiles:     parseSitePackages,
+		NormalizeNames: true,
+	},
+	{
+		Name:          "conda",
+		Command:       []string{"conda", "list", "--export"},
+		Parse:         parseCondaExport,
+		Database:      []string{"/opt/conda/conda-meta"},
+		ParseDatabase: parseCondaMeta,
+	},
+}
+
+var requirementPattern = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._+:-]*?)\s*(?:(>=|<=|==|!=|=|>|<)\s*([A-Za-z0-9]\S*))?\s*$`)
+
+type packageRequirement struct {
+	Name     string
+	Operator string
+	Version  string
+}
+
+func (r packageRequirement) String() string {
+	return r.Name + r.Operator + r.Version
+}
+
+func parseRequirement(s string) (packageRequirement, error) {
+	match := requirementPattern.FindStringSubmatch(s)
+	if match == nil {
+		return packageRequirement{}, fmt.Errorf("invalid package requirement %q", s)
+	}
+	return packageRequirement{Name: match[1], Operator: match[2], Version: match[3]}, nil
+}
+
+func (r packageRequirement) satisfiedBy(version string) bool {
+	if r.Operator == "" {
+		return true
+	}
+	cmp := compareVersions(version, r.Version)
+	switch r.Operator {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+func PackagesCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool, string, error) {
+	action := probe.Packages
+
+	var requirements []packageRequirement
+	for _, s := range action.Require {
+		r, err := parseRequirement(s)
+		if err != nil {
+			return false, "", err
+		}
+		requirements = append(requirements, r)
+	}
+
+	managers := packageManagers
+	if action.Manager != "" {
+		managers = nil
+		for _, m := range packageManagers {
+			if m.Name == action.Manager {
+				managers = append(managers, m)
+			}
+		}
+		if len(managers) == 0 {
+			return false, "", fmt.Errorf("unknown package manager '%s'", action.Manager)
+		}
+	}
+
+	inventory := map[string][]installedPackage{}
+	for _, m := range managers {
+		if packages, ok := m.installed(c); ok {
+			inventory[m.Name] = packages
+		}
+	}
+	if len(inventory) == 0 {
+		return false, "", fmt.Errorf("no supported package manager found in container")
+	}
+
+	var b strings.Builder
+	passed := true
+	for _, r := range requirements {
+		found := findPackage(inventory, r)
+		if found == "" {
+			passed = false
+			fmt.Fprintf(&b, "%s is not installed%s\n", r, installedVersions(inventory, r.Name))
+		} else {
+			fmt.Fprintf(&b, "%s is installed (%s)\n", r, found)
+		}
+	}
+	return passed, b.String(), nil
+}
+
+// installed lists the packages known to a package manager, and whether the
+// package manager was found in the container at all
+func (m packageManager) installed(c container.ContainerInterface) ([]installedPackage, bool) {
+	out, err := c.Exec(m.Command...)
+	if err == nil {
+		return m.Parse(out), true
+	}
+	files := map[string][]byte{}
+	for _, database := range m.Database {
+		contents, err := c.ReadFiles(database)
+		if err != nil {
+			continue
+		}
+		for name, b := range contents {
+			files[name] = b
+		}
+	}
+	if len(files) > 0 {
+		return m.ParseDatabase(files), true
+	}
+	if m.ParseFiles != nil {
+		if index, err := c.Files(); err == nil {
+			if packages := m.ParseFiles(index); len(packages) > 0 {
+				return packages, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (m packageManager) sameName(a, b string) bool {
+	if m.NormalizeNames {
+		return normalizePackageName(a) == normalizePackageName(b)
+	}
+	return a == b
+}
+
+func findPackage(inventory map[string][]installedPackage, r packageRequirement) string {
+	for _, manager := range sortedManagers(inventory) {
+		for _, p := range inventory[manager] {
+			if lookupManager(manager).sameName(p.Name, r.Name) && r.satisfiedBy(p.Version) {
+				return fmt.Sprintf("%s %s", manager, p.Version)
+			}
+		}
+	}
+	return ""
+}
+
+func installedVersions(inventory map[string][]installedPackage, name string) string {
+	var versions []string
+	for _, manager := range sortedManagers(inventory) {
+		for _, p := range inventory[manager] {
+			if lookupManager(manager).sameName(p.Name, name) {
+				versions = append(versions, fmt.Sprintf("%s %s", manager, p.Version))
+			}
+		}
+	}
+	if len(versions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (found %s)", strings.Join(versions, ", "))
+}
+
+func lookupManager(name string) packageManager {
+	for _, m := range packageManagers {
+		if m.Name == name {
+			return m
+		}
+	}
+	return packageManager{Name: name}
+}
+
+func sortedManagers(inventory map[string][]installedPackage) []string {
+	var managers []string
+	for manager := range inventory {
+		managers = append(managers, manager)
+	}
+	sort.Strings(managers)
+	return managers
+}
+
+// Python package names are case insensitive and treat '-', '_' and '.' the same
+func normalizePackageName(name string) string {
+	return strings.NewReplacer("_", "-", ".", "-").Replace(strings.ToLower(name))
+}
+
+func parseTabSeparated(out string) []installedPackage {
+	var packages []installedPackage
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) >= 2 && fields[0] != "" {
+			packages = append(packages, installedPackage{Name: fields[0], Version: fields[1]})
+		}
+	}
+	return packages
+}
+
+func parseDpkgQuery(out string) []installedPackage {
+	var packages []installedPackage
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		// Only count packages which are installed, not removed ones with leftover config
+		if len(fields) == 3 && strings.HasPrefix(fields[2], "ii") {
+			packages = append(packages, installedPackage{Name: fields[0], Version: fields[1]})
+		}
+	}
+	return packages
+}
+
+// parseDpkgDatabase reads /var/lib/dpkg/status, and the status.d directory
+// used by distroless images which have no dpkg binary
+func parseDpkgDatabase(files map[string][]byte) []installedPackage {
+	var packages []installedPackage
+	for name, contents := range files {
+		if path.Base(name) != "status" && path.Base(path.Dir(name)) != "status.d" {
+			continue
+		}
+		for _, stanza := range parseStanzas(string(contents), ": ") {
+			status := stanza["Status"]
+			if stanza["Package"] == "" || (status != "" && !strings.HasSuffix(status, " installed")) {
+				continue
+			}
+			packages = append(packages, installedPackage{Name: stanza["Package"], Version: stanza["Version"]})
+		}
+	}
+	return packages
+}
+
+// parseApkInfo reads lines like 'musl-1.2.4-r2', where the version is the last
+// two dash separated fields
+func parseApkInfo(out string) []installedPackage {
+	var packages []installedPackage
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		parts := strings.Split(line, "-")
+		if len(parts) < 3 {
+			continue
+		}
+		packages = append(packages, installedPackage{
+			Name:    strings.Join(parts[:len(parts)-2], "-"),
+			Version: strings.Join(parts[len(parts)-2:], "-"),
+		})
+	}
+	return packages
+}
+
+func parseApkDatabase(files map[string][]byte) []installedPackage {
+	var packages []installedPackage
+	for _, contents := range files {
+		for _, stanza := range parseStanzas(string(contents), ":") {
+			if stanza["P"] != "" {
+				packages = append(packages, installedPackage{Name: stanza["P"], Version: stanza["V"]})
+			}
+		}
+	}
+	return packages
+}
+
+func parsePipFreeze(out string) []installedPackage {
+	var packages []installedPackage
+	for _, line := range strings.Split(out, "\n") {
+		if name, version, ok := strings.Cut(strings.TrimSpace(line), "=="); ok {
+			packages = append(packages, installedPackage{Name: name, Version: version})
+		}
+	}
+	return packages
+}
+
+// parseSitePackages finds the packages installed in site-packages and
+// dist-packages directories from their metadata directory names, which are
+// 'name-version.dist-info' or 'name-version[-pyX.Y].egg-info'
+func parseSitePackages(files []container.FileInfo) []installedPackage {
+	var packages []installedPackage
+	for _, f := range files {
+		dir := path.Base(path.Dir(f.Path))
+		if dir != "site-packages" && dir != "dist-packages" {
+			continue
+		}
+		base := path.Base(f.Path)
+		stem := strings.TrimSuffix(strings.TrimSuffix(base, ".dist-info"), ".egg-info")
+		if stem == base {
+			continue
+		}
+		fields := strings.Split(stem, "-")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		packages = append(packages, installedPackage{Name: fields[0], Version: fields[1]})
+	}
+	return packages
+}
+
+func parseCondaExport(out string) []installedPackage {
+	var packages []installedPackage
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(strings.TrimSpace(line), "=")
+		if len(fields) >= 2 {
+			packages = append(packages, installedPackage{Name: fields[0], Version: fields[1]})
+		}
+	}
+	return packages
+}
+
+func parseCondaMeta(files map[string][]byte) []installedPackage {
+	var packages []installedPackage
+	for name, contents := range files {
+		if path.Ext(name) != ".json" {
+			continue
+		}
+		var meta struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(contents, &meta); err == nil && meta.Name != "" {
+			packages = append(packages, installedPackage{Name: meta.Name, Version: meta.Version})
+		}
+	}
+	return packages
+}
+
+// parseStanzas splits a database of blank line separated records of
+// 'Key<sep>Value' lines, ignoring continuation lines
+func parseStanzas(contents string, sep string) []map[string]string {
+	var stanzas []map[string]string
+	stanza := map[string]string{}
+	for _, line := range strings.Split(contents, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(stanza) > 0 {
+				stanzas = append(stanzas, stanza)
+				stanza = map[string]string{}
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, sep); ok {
+			stanza[key] = strings.TrimSpace(value)
+		}
+	}
+	if len(stanza) > 0 {
+		stanzas = append(stanzas, stanza)
+	}
+	return stanzas
+}
+
+// compareVersions compares two version strings in the style of dpkg, which
+// works well enough for the versions of every supported package manager.
+// Digit runs are compared numerically and '~' sorts before anything else.
+func compareVersions(a, b string) int {
+	epochA, a := splitEpoch(a)
+	epochB, b := splitEpoch(b)
+	if epochA != epochB {
+		return compareNumbers(epochA, epochB)
+	}
+	for a != "" || b != "" {
+		var partA, partB string
+		partA, a = splitVersionPart(a, false)
+		partB, b = splitVersionPart(b, false)
+		if cmp := compareNonDigits(partA, partB); cmp != 0 {
+			return cmp
+		}
+		partA, a = splitVersionPart(a, true)
+		partB, b = splitVersionPart(b, true)
+		if cmp := compareNumbers(partA, partB); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+func splitEpoch(v string) (string, string) {
+	if epoch, rest, ok := strings.Cut(v, ":"); ok && strings.IndexFunc(epoch, func(r rune) bool { return !unicode.IsDigit(r) }) == -1 {
+		return epoch, rest
+	}
+	return "0", v
+}
+
+func splitVersionPart(v string, digits bool) (string, string) {
+	i := strings.IndexFunc(v, func(r rune) bool { return unicode.IsDigit(r) != digits })
+	if i == -1 {
+		return v, ""
+	}
+	return v[:i], v[i:]
+}
+
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareNonDigits(a, b string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if cmp := versionCharOrder(a, i) - versionCharOrder(b, i); cmp != 0 {
+			if cmp < 0 {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionCharOrder ranks '~' lowest, then the end of the string, then letters, then everything else
+func versionCharOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	switch c := s[i]; {
+	case c == '~':
+		return -1
+	case unicode.IsLetter(rune(c)):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
@@ -0,0 +1,115 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"os"
+	"testing"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, compareVersions("3.9", "3.9"))
+	assert.Equal(1, compareVersions("3.11.4", "3.9"))
+	assert.Equal(1, compareVersions("3.9.2", "3.9"))
+	assert.Equal(-1, compareVersions("1.0~rc1", "1.0"))
+	assert.Equal(1, compareVersions("1:1.0", "2.0"))
+	assert.Equal(1, compareVersions("8u382-ga-1~deb12u1", "8u292"))
+	assert.Equal(-1, compareVersions("1.2.3-r0", "1.2.3-r1"))
+}
+
+func TestParseRequirement(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := parseRequirement("python>=3.9")
+	assert.Nil(err)
+	assert.Equal(packageRequirement{Name: "python", Operator: ">=", Version: "3.9"}, r)
+	assert.True(r.satisfiedBy("3.10.12"))
+	assert.False(r.satisfiedBy("3.8.10"))
+
+	r, err = parseRequirement("openjdk-8-jre")
+	assert.Nil(err)
+	assert.Equal("openjdk-8-jre", r.Name)
+	assert.True(r.satisfiedBy("8u382-ga-1"))
+
+	_, err = parseRequirement("python >= ")
+	assert.NotNil(err)
+}
+
+func TestParsePackageDatabases(t *testing.T) {
+	assert := assert.New(t)
+
+	dpkg := parseDpkgDatabase(map[string][]byte{
+		"status":              []byte("Package: coreutils\nStatus: install ok installed\nVersion: 9.1-1\nDescription: GNU\n core utilities\n\nPackage: vim\nStatus: deinstall ok config-files\nVersion: 9.0\n"),
+		"status.d/base-files": []byte("Package: base-files\nVersion: 12.4\n"),
+	})
+	assert.ElementsMatch([]installedPackage{{"coreutils", "9.1-1"}, {"base-files", "12.4"}}, dpkg)
+
+	apk := parseApkDatabase(map[string][]byte{
+		"installed": []byte("C:Q1abc=\nP:musl\nV:1.2.4-r2\n\nP:busybox\nV:1.36.1-r5\n"),
+	})
+	assert.Equal([]installedPackage{{"musl", "1.2.4-r2"}, {"busybox", "1.36.1-r5"}}, apk)
+
+	assert.Equal([]installedPackage{{"ca-certificates-bundle", "20230506-r0"}}, parseApkInfo("ca-certificates-bundle-20230506-r0\n"))
+	assert.Equal([]installedPackage{{"numpy", "1.26.0"}}, parsePipFreeze("numpy==1.26.0\n-e git+https://example.com\n"))
+	assert.Equal([]installedPackage{{"python", "3.11.5"}}, parseCondaExport("# platform: linux-64\npython=3.11.5=h955ad1f_0\n"))
+}
+
+func TestParseSitePackages(t *testing.T) {
+	assert := assert.New(t)
+
+	packages := parseSitePackages([]container.FileInfo{
+		{Path: "usr/local/lib/python3.11/site-packages/numpy-1.26.4.dist-info", Mode: os.ModeDir},
+		{Path: "usr/local/lib/python3.11/site-packages/numpy-1.26.4.dist-info/METADATA"},
+		{Path: "usr/lib/python3/dist-packages/PyYAML-6.0.1-py3.11.egg-info"},
+		{Path: "usr/lib/python3/dist-packages/numpy"},
+		{Path: "home/user/foo-1.0.dist-info"},
+	})
+	assert.Equal([]installedPackage{{"numpy", "1.26.4"}, {"PyYAML", "6.0.1"}}, packages)
+}
+
+func TestPackagesCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	// Without python3 in the container pip packages are found from site-packages
+	c := &fakeContainer{
+		exec: map[string]string{
+			"dpkg-query -W -f=${Package}\t${Version}\t${db:Status-Abbrev}\n": "python3-yaml\t6.0-3\tii \n",
+		},
+		files: []container.FileInfo{
+			{Path: "usr/lib/python3/dist-packages/typing_extensions-4.9.0.dist-info", Mode: os.ModeDir},
+		},
+	}
+	probe := &canaryv1.Probe{Packages: &canaryv1.PackagesAction{Require: []string{"typing-extensions>=4", "python3-yaml"}}}
+	passed, out, err := PackagesCheck(c, probe)
+	assert.Nil(err)
+	assert.True(passed, out)
+	assert.Contains(out, "typing-extensions>=4 is installed (pip 4.9.0)")
+
+	// Only pip package names are normalised
+	probe = &canaryv1.Probe{Packages: &canaryv1.PackagesAction{Require: []string{"Python3_Yaml"}}}
+	passed, out, err = PackagesCheck(c, probe)
+	assert.Nil(err)
+	assert.False(passed)
+	assert.Contains(out, "Python3_Yaml is not installed")
+}
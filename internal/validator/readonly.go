@@ -0,0 +1,74 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+)
+
+var changeKinds = map[string]string{
+	"A": "created",
+	"C": "modified",
+	"D": "deleted",
+}
+
+func ReadOnlyRootFilesystemCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool, string, error) {
+	action := probe.ReadOnlyRootFilesystem
+	changes, err := c.Diff()
+	if err != nil {
+		return false, "", fmt.Errorf("failed to diff container filesystem: %w", err)
+	}
+
+	var writes []container.FileChange
+	for _, change := range changes {
+		if !isAllowed(change.Path, action.Allow) {
+			writes = append(writes, change)
+		}
+	}
+
+	var b strings.Builder
+	for _, change := range writes {
+		// Every parent of a written path shows up as changed, only list the paths that were written to
+		if change.Kind == "C" && hasChildChange(change.Path, changes) {
+			continue
+		}
+		kind, ok := changeKinds[change.Kind]
+		if !ok {
+			kind = change.Kind
+		}
+		fmt.Fprintf(&b, "%s: %s\n", change.Path, kind)
+	}
+	if b.Len() == 0 {
+		return true, "", nil
+	}
+	return false, "paths written outside of volumes, mount an emptyDir or tmpfs over them:\n" + b.String(), nil
+}
+
+func hasChildChange(parent string, changes []container.FileChange) bool {
+	prefix := strings.TrimSuffix(parent, "/") + "/"
+	for _, change := range changes {
+		if strings.HasPrefix(change.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"testing"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyRootFilesystemCheck(t *testing.T) {
+	assert := assert.New(t)
+	c := &fakeContainer{
+		changes: []container.FileChange{
+			{Kind: "C", Path: "/var"},
+			{Kind: "C", Path: "/var/cache"},
+			{Kind: "A", Path: "/var/cache/app.db"},
+			{Kind: "C", Path: "/etc"},
+			{Kind: "D", Path: "/etc/motd"},
+			{Kind: "C", Path: "/run"},
+			{Kind: "A", Path: "/run/app.pid"},
+		},
+	}
+
+	passed, out, err := ReadOnlyRootFilesystemCheck(c, &canaryv1.Probe{
+		ReadOnlyRootFilesystem: &canaryv1.ReadOnlyRootFilesystemAction{Allow: []string{"/run/"}},
+	})
+	assert.Nil(err)
+	assert.False(passed)
+	assert.Contains(out, "/var/cache/app.db: created\n")
+	assert.Contains(out, "/etc/motd: deleted\n")
+	assert.NotContains(out, "/var:")
+	assert.NotContains(out, "/run")
+
+	passed, _, err = ReadOnlyRootFilesystemCheck(&fakeContainer{}, &canaryv1.Probe{
+		ReadOnlyRootFilesystem: &canaryv1.ReadOnlyRootFilesystemAction{},
+	})
+	assert.Nil(err)
+	assert.True(passed)
+}
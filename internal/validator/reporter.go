@@ -0,0 +1,201 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/nvidia/container-canary/internal/report"
+	"golang.org/x/term"
+)
+
+// Reporters accepted by the --reporter flag
+const (
+	ReporterAuto  = "auto"
+	ReporterTTY   = "tty"
+	ReporterPlain = "plain"
+)
+
+// Reporters lists the values accepted by the --reporter flag
+func Reporters() []string {
+	return []string{ReporterAuto, ReporterTTY, ReporterPlain}
+}
+
+// reporter is told about the progress of a validation as it happens. Calls are
+// made from a single goroutine and return once the event has been written.
+type reporter interface {
+	configLoaded(validator *canaryv1.Validator)
+	containerStarted(image string, validator *canaryv1.Validator, info *container.ContainerInfo, debug bool)
+	checkSkipped(check canaryv1.Check, reason string)
+	checkCompleted(result checkResult, completed int, total int)
+	diagnosticsCollected(d *report.Diagnostics)
+	validationFinished(passed bool, containerKept bool)
+	validationFailed(err error)
+	close()
+}
+
+// newReporter creates the named reporter writing to out. The TTY reporter calls
+// cancel when the user asks to quit.
+func newReporter(name string, out io.Writer, cancel func()) (reporter, error) {
+	switch name {
+	case ReporterAuto, "":
+		if tty, ok := openTTY(out); ok {
+			return newTTYReporter(tty, out, cancel), nil
+		}
+		return newPlainReporter(out), nil
+	case ReporterTTY:
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			return nil, fmt.Errorf("tty reporter needs a terminal: %w", err)
+		}
+		return newTTYReporter(tty, out, cancel), nil
+	case ReporterPlain:
+		return newPlainReporter(out), nil
+	default:
+		return nil, exitcode.Errorf(exitcode.ConfigError, "unknown reporter '%s', must be one of %s", name, strings.Join(Reporters(), ", "))
+	}
+}
+
+// openTTY opens the terminal for keyboard input when the output is going to an
+// interactive terminal and we aren't running in CI
+func openTTY(out io.Writer) (*os.File, bool) {
+	if ci := os.Getenv("CI"); ci == "true" || ci == "1" {
+		return nil, false
+	}
+	f, ok := out.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return nil, false
+	}
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return nil, false
+	}
+	return tty, true
+}
+
+// plainReporter writes one unstyled line per event, suitable for logs
+type plainReporter struct {
+	out      io.Writer
+	warnings int
+}
+
+func newPlainReporter(out io.Writer) *plainReporter {
+	return &plainReporter{out: out}
+}
+
+func (r *plainReporter) configLoaded(validator *canaryv1.Validator) {
+	fmt.Fprintln(r.out, "Starting container")
+}
+
+func (r *plainReporter) containerStarted(image string, validator *canaryv1.Validator, info *container.ContainerInfo, debug bool) {
+	for _, line := range startedLines(image, validator, info, debug, unstyled) {
+		fmt.Fprintln(r.out, line)
+	}
+}
+
+func (r *plainReporter) checkSkipped(check canaryv1.Check, reason string) {
+	fmt.Fprintln(r.out, skippedLine(check, reason, unstyled))
+}
+
+func (r *plainReporter) checkCompleted(result checkResult, completed int, total int) {
+	if isWarning(result) {
+		r.warnings++
+	}
+	for _, line := range resultLines(result, resultStatus) {
+		fmt.Fprintln(r.out, line)
+	}
+}
+
+func (r *plainReporter) diagnosticsCollected(d *report.Diagnostics) {
+	for _, line := range diagnosticsLines(d) {
+		fmt.Fprintln(r.out, line)
+	}
+}
+
+func (r *plainReporter) validationFinished(passed bool, containerKept bool) {
+	for _, line := range finishedLines(passed, containerKept, r.warnings, unstyled, unstyled, unstyled) {
+		fmt.Fprintln(r.out, line)
+	}
+}
+
+func (r *plainReporter) validationFailed(err error) {
+	fmt.Fprintf(r.out, "Error: %s\n", err.Error())
+}
+
+func (r *plainReporter) close() {}
+
+func unstyled(s string) string {
+	return s
+}
+
+// The lines below are shared by both reporters, which style them differently
+
+func startedLines(image string, validator *canaryv1.Validator, info *container.ContainerInfo, debug bool, highlight func(string) string) []string {
+	var lines []string
+	if info != nil && debug {
+		lines = append(lines, fmt.Sprintf("Running container with command '%s'", info.RunCommand))
+	}
+	if info != nil && validator.SecurityContext != nil && validator.SecurityContext.RunAsArbitraryUser {
+		lines = append(lines, fmt.Sprintf("Running as arbitrary user %s", highlight(info.Config.User)))
+	}
+	return append(lines, fmt.Sprintf("Validating %s against %s", highlight(image), highlight(validator.Name)))
+}
+
+func resultLines(result checkResult, status func(checkResult) string) []string {
+	lines := []string{fmt.Sprintf(" %-50s [%s]", result.Description, status(result))}
+	if !result.Passed && result.Output != "" {
+		lines = append(lines, indentOutput(result.Output))
+	}
+	return lines
+}
+
+func skippedLine(check canaryv1.Check, reason string, style func(string) string) string {
+	description := check.Description
+	if description == "" {
+		description = check.Name
+	}
+	return fmt.Sprintf(" %-50s [%s]", description, style("skipped - "+reason))
+}
+
+func diagnosticsLines(d *report.Diagnostics) []string {
+	return []string{"Container diagnostics:", indentOutput(d.String())}
+}
+
+func finishedLines(passed bool, containerKept bool, warnings int, passedStyle, failedStyle, warningStyle func(string) string) []string {
+	var lines []string
+	line := failedStyle("validation failed")
+	if passed {
+		line = passedStyle("validation passed")
+	}
+	if warnings == 1 {
+		line += " with " + warningStyle("1 warning")
+	} else if warnings > 1 {
+		line += " with " + warningStyle(fmt.Sprintf("%d warnings", warnings))
+	}
+	lines = append(lines, line)
+	if containerKept {
+		lines = append(lines, "Leaving container running for debugging...")
+	}
+	return lines
+}
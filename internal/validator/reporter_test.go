@@ -0,0 +1,99 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlainReporter(t *testing.T) {
+	assert := assert.New(t)
+	var out bytes.Buffer
+	r, err := newReporter(ReporterPlain, &out, func() {})
+	assert.Nil(err)
+
+	validator := &canaryv1.Validator{Name: "example"}
+	r.configLoaded(validator)
+	r.containerStarted("example:latest", validator, &container.ContainerInfo{RunCommand: "docker run example:latest"}, true)
+	r.checkSkipped(canaryv1.Check{Name: "http"}, "by --skip slow")
+	r.checkCompleted(checkResult{Description: "Has a shell", Passed: true}, 1, 2)
+	r.checkCompleted(checkResult{Description: "Has bash", Output: "bash: not found\n"}, 2, 2)
+	r.validationFinished(false, true)
+	r.close()
+
+	assert.Equal(`Starting container
+Running container with command 'docker run example:latest'
+Validating example:latest against example
+ http                                               [skipped - by --skip slow]
+ Has a shell                                        [passed]
+ Has bash                                           [failed]
+    bash: not found
+validation failed
+Leaving container running for debugging...
+`, out.String())
+	assert.NotContains(out.String(), "Press q to quit")
+}
+
+func TestPlainReporterWarnings(t *testing.T) {
+	assert := assert.New(t)
+	var out bytes.Buffer
+	r := newPlainReporter(&out)
+	r.checkCompleted(checkResult{Description: "Sets CORS headers", Severity: canaryv1.SeverityWarning}, 1, 3)
+	r.checkCompleted(checkResult{Description: "Uses a recent base", Severity: canaryv1.SeverityInfo}, 2, 3)
+	r.checkCompleted(checkResult{Description: "Has bash", Severity: canaryv1.SeverityError}, 3, 3)
+	r.validationFinished(false, false)
+
+	assert.Equal(` Sets CORS headers                                  [warning]
+ Uses a recent base                                 [info]
+ Has bash                                           [failed]
+validation failed with 1 warning
+`, out.String())
+}
+
+func TestPlainReporterError(t *testing.T) {
+	assert := assert.New(t)
+	var out bytes.Buffer
+	r := newPlainReporter(&out)
+	r.validationFailed(errors.New("no checks found"))
+	assert.Equal("Error: no checks found\n", out.String())
+}
+
+func TestNewReporter(t *testing.T) {
+	assert := assert.New(t)
+	var out bytes.Buffer
+
+	// A buffer is never a terminal so auto falls back to plain output
+	r, err := newReporter(ReporterAuto, &out, func() {})
+	assert.Nil(err)
+	assert.IsType(&plainReporter{}, r)
+
+	t.Setenv("CI", "true")
+	r, err = newReporter("", os.Stderr, func() {})
+	assert.Nil(err)
+	assert.IsType(&plainReporter{}, r)
+
+	_, err = newReporter("fancy", &out, func() {})
+	assert.EqualError(err, "unknown reporter 'fancy', must be one of auto, tty, plain")
+}
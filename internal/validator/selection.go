@@ -0,0 +1,73 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/exitcode"
+)
+
+// checkSelection picks the checks to run from the --only and --skip flags,
+// whose values each match checks by name or by tag
+type checkSelection struct {
+	only []string
+	skip []string
+}
+
+// skipReason explains why a check won't be run, or is empty if it will. It is
+// shown after "skipped - ", so doesn't say skipped itself.
+func (s checkSelection) skipReason(check canaryv1.Check) string {
+	if len(s.only) > 0 && !slices.ContainsFunc(s.only, func(value string) bool { return matchesCheck(check, value) }) {
+		return fmt.Sprintf("not selected by --only %s", strings.Join(s.only, ","))
+	}
+	for _, value := range s.skip {
+		if matchesCheck(check, value) {
+			return fmt.Sprintf("by --skip %s", value)
+		}
+	}
+	return ""
+}
+
+func matchesCheck(check canaryv1.Check, value string) bool {
+	return check.Name == value || slices.Contains(check.Tags, value)
+}
+
+// validate makes sure every value matches a check, so that a typo doesn't
+// quietly run the wrong checks, and that something is left to run
+func (s checkSelection) validate(validator *canaryv1.Validator) error {
+	for _, flag := range []struct {
+		name   string
+		values []string
+	}{{"only", s.only}, {"skip", s.skip}} {
+		for _, value := range flag.values {
+			if !slices.ContainsFunc(validator.Checks, func(check canaryv1.Check) bool { return matchesCheck(check, value) }) {
+				return exitcode.Errorf(exitcode.ConfigError, "--%s %s matches no check names or tags in %s", flag.name, value, validator.Name)
+			}
+		}
+	}
+	for _, check := range validator.Checks {
+		if s.skipReason(check) == "" {
+			return nil
+		}
+	}
+	return exitcode.Errorf(exitcode.ConfigError, "no checks selected to run, --only and --skip exclude all of them")
+}
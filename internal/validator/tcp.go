@@ -25,12 +25,12 @@ import (
 	"github.com/nvidia/container-canary/internal/container"
 )
 
-func TCPSocketCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool, error) {
+func TCPSocketCheck(c container.ContainerInterface, probe *canaryv1.Probe) (bool, string, error) {
 	action := probe.TCPSocket
 	address := fmt.Sprintf("localhost:%d", action.Port)
 	_, err := net.Dial("tcp", address)
 	if err != nil {
-		return false, nil
+		return false, "", nil
 	}
-	return true, nil
+	return true, "", nil
 }
@@ -19,37 +19,134 @@ package validator
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
 	"github.com/nvidia/container-canary/internal/container"
+	"github.com/nvidia/container-canary/internal/report"
 )
 
+// ttyReporter shows a spinner and progress bar on an interactive terminal,
+// printing results above them as they arrive
+type ttyReporter struct {
+	program  *tea.Program
+	tty      *os.File
+	done     chan struct{}
+	warnings int
+}
+
+// reportEvent updates the model and prints lines above the progress bar. The
+// ack channel is closed once the lines have been handed to the program.
+type reportEvent struct {
+	update func(*model)
+	lines  []string
+	ack    chan struct{}
+}
+
 type model struct {
-	sub                     chan checkResult
-	container               container.ContainerInterface
-	containerStarted        bool
-	containerStartupTimeout int
-	results                 []checkResult
-	allChecksPassed         bool
-	spinner                 spinner.Model
-	progress                progress.Model
-	debug                   bool
-	image                   string
-	validator               *canaryv1.Validator
-	configPath              string
-	err                     error
-	tty                     bool
+	cancel           func()
+	validator        *canaryv1.Validator
+	containerStarted bool
+	completed        int
+	// Number of checks selected to run
+	total           int
+	allChecksPassed bool
+	finished        bool
+	quitting        bool
+	spinner         spinner.Model
+	progress        progress.Model
+}
+
+func newTTYReporter(tty *os.File, out io.Writer, cancel func()) *ttyReporter {
+	m := model{
+		cancel:          cancel,
+		allChecksPassed: true,
+		spinner:         spinner.New(),
+		progress:        progress.New(progress.WithSolidFill("#f2e63a")),
+	}
+	r := &ttyReporter{
+		program: tea.NewProgram(m, tea.WithInput(tty), tea.WithOutput(out)),
+		tty:     tty,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(r.done)
+		_, _ = r.program.Run()
+	}()
+	return r
+}
+
+// send delivers an event to the program and waits for it to be handled
+func (r *ttyReporter) send(e reportEvent) {
+	e.ack = make(chan struct{})
+	r.program.Send(e)
+	select {
+	case <-e.ack:
+	case <-r.done:
+	}
+}
+
+func (r *ttyReporter) configLoaded(validator *canaryv1.Validator) {
+	r.send(reportEvent{update: func(m *model) { m.validator = validator }})
+}
+
+func (r *ttyReporter) containerStarted(image string, validator *canaryv1.Validator, info *container.ContainerInfo, debug bool) {
+	r.send(reportEvent{
+		update: func(m *model) { m.containerStarted = true },
+		lines:  startedLines(image, validator, info, debug, highlightStyle),
+	})
+}
+
+func (r *ttyReporter) checkSkipped(check canaryv1.Check, reason string) {
+	r.send(reportEvent{update: func(m *model) {}, lines: []string{skippedLine(check, reason, helpStyle)}})
+}
+
+func (r *ttyReporter) checkCompleted(result checkResult, completed int, total int) {
+	if isWarning(result) {
+		r.warnings++
+	}
+	r.send(reportEvent{
+		update: func(m *model) {
+			m.completed = completed
+			m.total = total
+			if result.failed() {
+				m.allChecksPassed = false
+			}
+		},
+		lines: resultLines(result, getStatus),
+	})
+}
+
+func (r *ttyReporter) diagnosticsCollected(d *report.Diagnostics) {
+	r.send(reportEvent{update: func(m *model) {}, lines: diagnosticsLines(d)})
+}
+
+func (r *ttyReporter) validationFinished(passed bool, containerKept bool) {
+	r.send(reportEvent{
+		update: func(m *model) { m.finished = true },
+		lines:  finishedLines(passed, containerKept, r.warnings, passedStyle, failedStyle, warningStyle),
+	})
+}
+
+func (r *ttyReporter) validationFailed(err error) {
+	r.send(reportEvent{
+		update: func(m *model) { m.finished = true },
+		lines:  []string{fmt.Sprintf("Error: %s", err.Error())},
+	})
+}
+
+func (r *ttyReporter) close() {
+	r.program.Quit()
+	<-r.done
+	r.tty.Close()
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(
-		spinner.Tick,
-		waitForChecks(m.sub),
-		loadConfig(m.configPath),
-	)
+	return spinner.Tick
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -58,16 +155,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Application events
 	case tea.KeyMsg:
 		return handleKeypress(m, msg)
-	case configLoaded:
-		return handleConfigLoaded(m, msg)
-	case containerStarted:
-		return handleContainerStarted(m, msg)
-	case containerStopped:
-		return m, tea.Quit
-	case containerFailed:
-		return handleContainerFailed(m, msg)
-	case checkResult:
-		return handleCheckResult(m, msg)
+	case reportEvent:
+		return handleReportEvent(m, msg)
 
 	// UI events
 	case spinner.TickMsg:
@@ -85,109 +174,58 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) View() string {
+	if m.finished {
+		return ""
+	}
 	help := helpStyle("Press q to quit...")
+	if m.quitting {
+		return fmt.Sprintf("%s Stopping\n", m.spinner.View())
+	}
 
-	if m.tty {
-		if m.validator == nil {
-			return fmt.Sprintf("%s Loading config\n", m.spinner.View()) + help
-		}
-
-		if !m.containerStarted {
-			return fmt.Sprintf("%s Starting container\n", m.spinner.View()) + help
-		}
-
-		if len(m.results) < len(m.validator.Checks) {
-			return m.progress.View() + "\n" + help
-		}
+	if m.validator == nil {
+		return fmt.Sprintf("%s Loading config\n", m.spinner.View()) + help
 	}
 
-	return help
-}
+	if !m.containerStarted {
+		return fmt.Sprintf("%s Starting container\n", m.spinner.View()) + help
+	}
 
-func (m model) Passed() bool {
-	return m.allChecksPassed
+	return m.progress.View() + "\n" + help
 }
 
 func handleKeypress(m model, keypress tea.KeyMsg) (model, tea.Cmd) {
 	switch keypress.String() {
-	// These keys should exit the program.
+	// These keys stop the validation, which removes the container and closes the reporter
 	case "ctrl+c", "q":
-		m.allChecksPassed = false
-		if m.containerStarted {
-			return m, shutdown(m.container)
-		} else {
-			return m, tea.Quit
+		if !m.quitting {
+			m.quitting = true
+			m.cancel()
 		}
+		return m, nil
 	default:
 		return m, nil
 	}
-
-}
-
-func handleConfigLoaded(m model, msg configLoaded) (model, tea.Cmd) {
-	var commands []tea.Cmd
-	if msg.Error != nil {
-		m.err = msg.Error
-		return m, tea.Batch(tea.Printf("Error: %s\n", msg.Error.Error()), tea.Quit)
-	}
-	m.validator = msg.Config
-	if !m.tty {
-		commands = append(commands, tea.Printf("Starting container"))
-	}
-	commands = append(commands, startContainer(m.image, m.validator, m.containerStartupTimeout))
-	return m, tea.Batch(commands...)
-}
-
-func handleContainerStarted(m model, msg containerStarted) (model, tea.Cmd) {
-	var commands []tea.Cmd
-	m.containerStarted = true
-	m.container = msg.Container
-
-	if m.debug {
-		status, _ := m.container.Status()
-		commands = append(commands, tea.Printf("Running container with command '%s'", status.RunCommand))
-	}
-	commands = append(commands, tea.Printf("Validating %s against %s", highlightStyle(m.image), highlightStyle(m.validator.Name)))
-	for _, check := range m.validator.Checks {
-		commands = append(commands, runCheck(m.sub, m.container, check))
-	}
-	return m, tea.Batch(commands...)
-}
-
-func handleContainerFailed(m model, msg containerFailed) (model, tea.Cmd) {
-	m.err = msg.Error
-	return m, tea.Batch(tea.Printf("Error: %s\n", m.err.Error()), tea.Quit)
 }
 
-func handleCheckResult(m model, msg checkResult) (model, tea.Cmd) {
-	var commands []tea.Cmd
+func handleReportEvent(m model, e reportEvent) (model, tea.Cmd) {
+	e.update(&m)
 	var printCommands []tea.Cmd
-	m.results = append(m.results, msg)
-	if !msg.Passed {
-		m.allChecksPassed = false
+	for _, line := range e.lines {
+		printCommands = append(printCommands, tea.Println(line))
 	}
-	printCommands = append(printCommands,
-		tea.Printf(" %-50s [%s]", msg.Description, getStatus(msg.Passed, msg.Error)))
-	if len(m.results) == len(m.validator.Checks) {
-		if m.allChecksPassed {
-			printCommands = append(printCommands, tea.Println(passedStyle("validation passed")))
-		} else {
-			printCommands = append(printCommands, tea.Println(failedStyle("validation failed")))
-		}
-		if !m.allChecksPassed && m.debug {
-			printCommands = append(printCommands, tea.Println("Leaving container running for debugging..."))
-		} else {
-			commands = append(commands, shutdown(m.container))
-		}
-	} else {
+	printCommands = append(printCommands, func() tea.Msg {
+		close(e.ack)
+		return nil
+	})
+
+	var progressCommand tea.Cmd
+	if m.total > 0 && m.completed > 0 {
 		if m.allChecksPassed {
 			m.progress.FullColor = "10"
 		} else {
 			m.progress.FullColor = "9"
 		}
-		commands = append(commands,
-			waitForChecks(m.sub),
-			m.progress.SetPercent(float64(len(m.results))/float64(len(m.validator.Checks))))
+		progressCommand = m.progress.SetPercent(float64(m.completed) / float64(m.total))
 	}
-	return m, tea.Sequence(tea.Sequence(printCommands...), tea.Batch(commands...))
+	return m, tea.Batch(tea.Sequence(printCommands...), progressCommand)
 }
@@ -18,175 +18,483 @@
 package validator
 
 import (
-	"bufio"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
-	"github.com/charmbracelet/bubbles/progress"
-	"github.com/charmbracelet/bubbles/spinner"
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
 	"github.com/nvidia/container-canary/internal/config"
 	"github.com/nvidia/container-canary/internal/container"
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/nvidia/container-canary/internal/report"
+	"github.com/nvidia/container-canary/internal/tracing"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var helpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render
 var passedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render
 var failedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render
 var highlightStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("6")).Render
+var warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render
 
 type checkResult struct {
+	// Position of the check in the validator
+	Index       int
+	Name        string
 	Description string
 	Passed      bool
-	Error       error
+	// The severity of the check once --strict has been applied
+	Severity string
+	Output   string
+	Error    error
+	Duration time.Duration
+	Attempts int
 }
 
-type containerFailed struct {
-	Error error
-}
-type containerStarted struct {
-	Container container.ContainerInterface
-}
-type containerStopped struct{}
-type configLoaded struct {
-	Config *canaryv1.Validator
-	Error  error
+// failed reports whether the check didn't pass and that fails validation
+func (r checkResult) failed() bool {
+	return !r.Passed && (r.Severity == "" || r.Severity == canaryv1.SeverityError)
 }
 
-type probeCallable func(container.ContainerInterface, *canaryv1.Probe) (bool, error)
+// ErrValidationFailed is returned when validation completed but not every check passed
+var ErrValidationFailed = errors.New("validation failed")
+
+var tracer = tracing.Tracer("github.com/nvidia/container-canary/internal/validator")
+
+type probeCallable func(container.ContainerInterface, *canaryv1.Probe) (bool, string, error)
+
+// validation runs the checks of a validator against an image and keeps track
+// of the results, telling a reporter about its progress
+type validation struct {
+	image          string
+	configPath     string
+	loadOptions    config.LoadOptions
+	startupTimeout int
+	debug          bool
+	arbitraryUser  bool
+	selection      checkSelection
+	strict         bool
+	newContainer   func(image string, validator *canaryv1.Validator) container.ContainerInterface
+
+	validator       *canaryv1.Validator
+	container       container.ContainerInterface
+	containerInfo   *container.ContainerInfo
+	containerKept   bool
+	diagnostics     *report.Diagnostics
+	results         []checkResult
+	allChecksPassed bool
+	err             error
+	startedAt       time.Time
+}
 
-func Validate(image string, configPath string, cmd *cobra.Command, debug bool) (bool, error) {
-	var tty io.Reader
-	isTty := true
-	tty, err := os.Open("/dev/tty")
+func Validate(image string, configPath string, cmd *cobra.Command, debug bool) (*report.Report, error) {
+	startupTimeout, err := cmd.Flags().GetInt("startup-timeout")
 	if err != nil {
-		tty = bufio.NewReader(os.Stdin)
-		isTty = false
+		return nil, err
 	}
-	startupTimeout, err := cmd.Flags().GetInt("startup-timeout")
+	arbitraryUser, err := cmd.Flags().GetBool("arbitrary-uid")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	m := model{
-		sub:                     make(chan checkResult),
-		configPath:              configPath,
-		containerStarted:        false,
-		containerStartupTimeout: startupTimeout,
-		spinner:                 spinner.New(),
-		progress:                progress.New(progress.WithSolidFill("#f2e63a")),
-		allChecksPassed:         true,
-		debug:                   debug,
-		image:                   image,
-		tty:                     isTty,
+	reporterName, err := cmd.Flags().GetString("reporter")
+	if err != nil {
+		return nil, err
 	}
-	p := tea.NewProgram(m, tea.WithInput(tty), tea.WithOutput(cmd.OutOrStderr()))
-	out, err := p.Run()
+	valuesFiles, err := cmd.Flags().GetStringArray("values")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	if out, ok := out.(model); ok {
-		return out.allChecksPassed, out.err
-	} else {
-		return false, errors.New("program returned unknown model")
+	set, err := cmd.Flags().GetStringArray("set")
+	if err != nil {
+		return nil, err
+	}
+	parameters, err := config.ParameterValues(valuesFiles, set)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := cmd.Flags().GetString("public-key")
+	if err != nil {
+		return nil, err
+	}
+	only, err := cmd.Flags().GetStringSlice("only")
+	if err != nil {
+		return nil, err
+	}
+	skip, err := cmd.Flags().GetStringSlice("skip")
+	if err != nil {
+		return nil, err
+	}
+	strict, err := cmd.Flags().GetBool("strict")
+	if err != nil {
+		return nil, err
 	}
 
+	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "validate", trace.WithAttributes(
+		attribute.String("canary.image", image),
+		attribute.String("canary.validator.source", configPath),
+	))
+	// The image is pulled before the reporter starts, as pulling prints progress
+	if err := pullImage(ctx, cmd, image); err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	r, err := newReporter(reporterName, cmd.OutOrStderr(), cancel)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+
+	v := newValidation(image, configPath, startupTimeout, debug, arbitraryUser)
+	v.loadOptions = config.LoadOptions{Parameters: parameters, PublicKey: publicKey}
+	v.selection = checkSelection{only: only, skip: skip}
+	v.strict = strict
+	v.run(ctx, r)
+	r.close()
+	err = v.err
+	if v.err == nil && !v.allChecksPassed {
+		err = exitcode.Wrap(v.failureCode(), ErrValidationFailed)
+	}
+	span.SetAttributes(attribute.Bool("canary.passed", v.allChecksPassed && v.err == nil))
+	endSpan(span, err)
+	return v.report(), err
 }
 
-func loadConfig(filePath string) tea.Cmd {
-	return func() tea.Msg {
-		var validatorConfig *canaryv1.Validator
-		var err error
+func newValidation(image string, configPath string, startupTimeout int, debug bool, arbitraryUser bool) *validation {
+	return &validation{
+		image:           image,
+		configPath:      configPath,
+		startupTimeout:  startupTimeout,
+		debug:           debug,
+		arbitraryUser:   arbitraryUser,
+		newContainer:    newDockerContainer,
+		allChecksPassed: true,
+		startedAt:       time.Now(),
+	}
+}
 
-		if strings.Contains(filePath, "://") {
-			validatorConfig, err = config.LoadValidatorFromURL(filePath)
-		} else {
-			validatorConfig, err = config.LoadValidatorFromFile(filePath)
+func newDockerContainer(image string, validator *canaryv1.Validator) container.ContainerInterface {
+	return container.New(image, validator.Env, validator.Ports, validator.Volumes, validator.Command, validator.DockerRunOptions, validator.SecurityContext)
+}
+
+// run loads the validator, starts the container and runs every check against
+// it. Cancelling the context stops waiting for checks and removes the container.
+func (v *validation) run(ctx context.Context, r reporter) {
+	_, span := tracer.Start(ctx, "loadConfig")
+	validator, err := loadConfig(v.configPath, v.loadOptions)
+	endSpan(span, err)
+	if err != nil {
+		v.fail(r, err)
+		return
+	}
+	applyArbitraryUser(validator, v.arbitraryUser)
+	if err := v.selection.validate(validator); err != nil {
+		v.fail(r, err)
+		return
+	}
+	v.validator = validator
+	r.configLoaded(validator)
+
+	c := v.newContainer(v.image, validator)
+	_, span = tracer.Start(ctx, "startContainer")
+	err = c.Start(v.startupTimeout)
+	endSpan(span, err)
+	if err != nil {
+		var startErr *container.StartError
+		if errors.As(err, &startErr) && startErr.Diagnostics != nil {
+			v.diagnose(r, startErr.Diagnostics)
 		}
-		if err != nil {
-			return configLoaded{
-				Config: nil,
-				Error:  err,
-			}
+		v.fail(r, err)
+		return
+	}
+	v.container = c
+	v.containerInfo, _ = c.Status()
+	if ctx.Err() != nil {
+		v.allChecksPassed = false
+		v.fail(r, exitcode.Errorf(exitcode.Interrupted, "validation cancelled"))
+		v.shutdown(ctx, r)
+		return
+	}
+	r.containerStarted(v.image, validator, v.containerInfo, v.debug)
+
+	// Checks which aren't selected are reported up front, progress only counts those which run
+	var selected []int
+	for i, check := range validator.Checks {
+		if reason := v.selection.skipReason(check); reason != "" {
+			r.checkSkipped(check, reason)
+		} else {
+			selected = append(selected, i)
 		}
-		if len(validatorConfig.Checks) == 0 {
-			return configLoaded{
-				Config: nil,
-				Error:  errors.New("no checks found"),
+	}
+	results := make(chan checkResult, len(selected))
+	for _, i := range selected {
+		go runCheck(ctx, results, c, i, validator.Checks[i])
+	}
+	for len(v.results) < len(selected) {
+		select {
+		case result := <-results:
+			result.Severity = v.severity(validator.Checks[result.Index])
+			v.results = append(v.results, result)
+			if result.failed() {
+				v.allChecksPassed = false
 			}
+			r.checkCompleted(result, len(v.results), len(selected))
+		case <-ctx.Done():
+			v.allChecksPassed = false
+			v.fail(r, exitcode.Errorf(exitcode.Interrupted, "validation cancelled"))
+			v.shutdown(ctx, r)
+			return
 		}
+	}
 
-		return configLoaded{
-			Config: validatorConfig,
-			Error:  nil,
-		}
+	if !v.allChecksPassed {
+		v.diagnose(r, container.CollectDiagnostics(c))
+	}
+	v.containerKept = !v.allChecksPassed && v.debug
+	r.validationFinished(v.allChecksPassed, v.containerKept)
+	if !v.containerKept {
+		v.shutdown(ctx, r)
 	}
 }
 
-func startContainer(image string, validator *canaryv1.Validator, startupTimeout int) tea.Cmd {
-	return func() tea.Msg {
-		container := container.New(image, validator.Env, validator.Ports, validator.Volumes, validator.Command, validator.DockerRunOptions)
-		err := container.Start(startupTimeout)
-		if err != nil {
-			return containerFailed{Error: err}
+// severity is how much a check failing matters, only errors fail validation
+// and --strict makes warnings errors too
+func (v *validation) severity(check canaryv1.Check) string {
+	if check.Severity == "" || (v.strict && check.Severity == canaryv1.SeverityWarning) {
+		return canaryv1.SeverityError
+	}
+	return check.Severity
+}
+
+func (v *validation) shutdown(ctx context.Context, r reporter) {
+	// Removal should still be traced when the validation was cancelled
+	_, span := tracer.Start(context.WithoutCancel(ctx), "shutdown")
+	err := v.container.Remove()
+	endSpan(span, err)
+	if err != nil {
+		v.fail(r, err)
+	}
+}
+
+// Only the end of the logs is kept in diagnostics, as that is usually where the problem is
+const maxDiagnosticLogLines = 50
+
+func (v *validation) diagnose(r reporter, d *container.Diagnostics) {
+	v.diagnostics = &report.Diagnostics{
+		RunCommand: d.RunCommand,
+		Status:     d.State.Status,
+		OOMKilled:  d.State.OOMKilled,
+		Error:      d.State.Error,
+		Logs:       tailLines(d.Logs, maxDiagnosticLogLines),
+	}
+	if d.State.Status == "exited" {
+		exitCode := d.State.ExitCode
+		v.diagnostics.ExitCode = &exitCode
+	}
+	if d.State.Health != nil {
+		v.diagnostics.Health = d.State.Health.Status
+	}
+	for _, change := range d.Writes {
+		v.diagnostics.Writes = append(v.diagnostics.Writes, change.Kind+" "+change.Path)
+	}
+	r.diagnosticsCollected(v.diagnostics)
+}
+
+// tailLines keeps the last n lines of some text, noting how many were dropped
+func tailLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) <= n {
+		return text
+	}
+	return fmt.Sprintf("... %d earlier lines omitted\n%s\n", len(lines)-n, strings.Join(lines[len(lines)-n:], "\n"))
+}
+
+func (v *validation) fail(r reporter, err error) {
+	v.err = err
+	r.validationFailed(err)
+}
+
+// failureCode picks the exit code for checks which didn't pass. A check which
+// failed outright means the image doesn't comply, whatever else went wrong.
+func (v *validation) failureCode() exitcode.Code {
+	code := exitcode.ChecksFailed
+	for _, result := range v.results {
+		if !result.failed() {
+			continue
+		}
+		if result.Error == nil {
+			return exitcode.ChecksFailed
 		}
-		return containerStarted{
-			Container: container,
+		if c := exitcode.Of(result.Error); code == exitcode.ChecksFailed || c == exitcode.Timeout {
+			code = c
 		}
 	}
+	return code
 }
 
-func shutdown(container container.ContainerInterface) tea.Cmd {
-	return func() tea.Msg {
-		err := container.Remove()
-		if err != nil {
-			return containerFailed{Error: err}
+// report summarises the validation, listing checks in the order of the validator
+func (v *validation) report() *report.Report {
+	r := &report.Report{
+		SchemaVersion:   report.SchemaVersion,
+		Validator:       report.Validator{Source: v.configPath},
+		Image:           report.Image{Name: v.image},
+		Passed:          v.allChecksPassed && v.err == nil,
+		StartedAt:       v.startedAt,
+		DurationSeconds: time.Since(v.startedAt).Seconds(),
+		Checks:          []report.Check{},
+		Diagnostics:     v.diagnostics,
+	}
+	if v.err != nil {
+		r.Error = v.err.Error()
+	}
+	if info, err := container.InspectImage(v.image); err == nil {
+		r.Image.Id = info.Id
+		if len(info.RepoDigests) > 0 {
+			_, r.Image.Digest, _ = strings.Cut(info.RepoDigests[0], "@")
 		}
-		return containerStopped{}
 	}
+	if v.containerInfo != nil {
+		r.Container = &report.Container{
+			Name:       strings.TrimPrefix(v.containerInfo.Name, "/"),
+			RunCommand: v.containerInfo.RunCommand,
+			Kept:       v.containerKept,
+		}
+		if v.validator != nil && v.validator.SecurityContext != nil && v.validator.SecurityContext.RunAsArbitraryUser {
+			r.Container.User = v.containerInfo.Config.User
+		}
+	}
+	if v.validator == nil {
+		return r
+	}
+
+	r.Validator.Name = v.validator.Name
+	r.Validator.Description = v.validator.Description
+	r.Validator.Documentation = v.validator.Documentation
+	results := map[int]checkResult{}
+	for _, result := range v.results {
+		results[result.Index] = result
+	}
+	for i, check := range v.validator.Checks {
+		c := report.Check{Name: check.Name, Description: check.Description, Status: report.StatusSkipped, Reason: v.selection.skipReason(check), Severity: v.severity(check)}
+		if result, ok := results[i]; ok {
+			c.DurationSeconds = result.Duration.Seconds()
+			c.Attempts = result.Attempts
+			c.Output = result.Output
+			if result.Error != nil {
+				c.Status = report.StatusError
+				c.Error = result.Error.Error()
+			} else if result.Passed {
+				c.Status = report.StatusPassed
+			} else {
+				c.Status = report.StatusFailed
+			}
+		}
+		r.Checks = append(r.Checks, c)
+	}
+	return r
 }
 
-func waitForChecks(sub chan checkResult) tea.Cmd {
-	return func() tea.Msg {
-		return <-sub
+func loadConfig(filePath string, options config.LoadOptions) (*canaryv1.Validator, error) {
+	validatorConfig, err := config.LoadValidator(filePath, options)
+	if err != nil {
+		return nil, err
 	}
+	if len(validatorConfig.Checks) == 0 {
+		return nil, exitcode.Errorf(exitcode.ConfigError, "no checks found")
+	}
+	return validatorConfig, nil
 }
 
-func runCheck(results chan<- checkResult, c container.ContainerInterface, check canaryv1.Check) tea.Cmd {
-	return func() tea.Msg {
-		var p bool
-		var err error
-		// TODO Make more SOLID (O)
-		if check.Probe.Exec != nil {
-			p, err = executeCheck(ExecCheck, c, &check.Probe)
-		} else if check.Probe.HTTPGet != nil {
-			p, err = executeCheck(HTTPGetCheck, c, &check.Probe)
-		} else if check.Probe.TCPSocket != nil {
-			p, err = executeCheck(TCPSocketCheck, c, &check.Probe)
-		} else {
-			results <- checkResult{check.Description, false, fmt.Errorf("check '%s' has no known probes", check.Name)}
-			return nil
+// applyArbitraryUser switches on arbitrary UID mode if forced and, when it is
+// on, makes sure something checks that the home directory is writable
+func applyArbitraryUser(validator *canaryv1.Validator, force bool) {
+	if force {
+		if validator.SecurityContext == nil {
+			validator.SecurityContext = &canaryv1.SecurityContext{}
 		}
-		results <- checkResult{check.Description, p, err}
-		return nil
+		validator.SecurityContext.RunAsArbitraryUser = true
+	}
+	if validator.SecurityContext == nil || !validator.SecurityContext.RunAsArbitraryUser {
+		return
+	}
+	for _, check := range validator.Checks {
+		if check.Probe.GroupWritable != nil {
+			return
+		}
+	}
+	validator.Checks = append(validator.Checks, canaryv1.Check{
+		Name:        "arbitrary-uid",
+		Description: "🎲 Home directory is writable by an arbitrary UID",
+		Probe: canaryv1.Probe{
+			GroupWritable:    &canaryv1.GroupWritableAction{},
+			TimeoutSeconds:   30,
+			PeriodSeconds:    1,
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+		},
+	})
+}
+
+func runCheck(ctx context.Context, results chan<- checkResult, c container.ContainerInterface, index int, check canaryv1.Check) {
+	ctx, span := tracer.Start(ctx, "check", trace.WithAttributes(
+		attribute.String("canary.check.name", check.Name),
+		attribute.String("canary.check.description", check.Description),
+	))
+	result := checkResult{Index: index, Name: check.Name, Description: check.Description}
+	start := time.Now()
+	var method probeCallable
+	// TODO Make more SOLID (O)
+	if check.Probe.Exec != nil {
+		method = ExecCheck
+	} else if check.Probe.HTTPGet != nil {
+		method = HTTPGetCheck
+	} else if check.Probe.TCPSocket != nil {
+		method = TCPSocketCheck
+	} else if check.Probe.ImageSize != nil {
+		method = ImageSizeCheck
+	} else if check.Probe.Packages != nil {
+		method = PackagesCheck
+	} else if check.Probe.Security != nil {
+		method = SecurityCheck
+	} else if check.Probe.ReadOnlyRootFilesystem != nil {
+		method = ReadOnlyRootFilesystemCheck
+	} else if check.Probe.GroupWritable != nil {
+		method = GroupWritableCheck
+	}
+	if method == nil {
+		result.Error = exitcode.Errorf(exitcode.ConfigError, "check '%s' has no known probes", check.Name)
+	} else {
+		result.Passed, result.Output, result.Attempts, result.Error = executeCheck(ctx, method, c, &check.Probe)
 	}
+	result.Duration = time.Since(start)
+	span.SetAttributes(attribute.Bool("canary.check.passed", result.Passed), attribute.Int("canary.check.attempts", result.Attempts))
+	endSpan(span, result.Error)
+	results <- result
 }
 
 // Run a check method with appropriate delay, retries and retry interval
-func executeCheck(method probeCallable, c container.ContainerInterface, probe *canaryv1.Probe) (bool, error) {
+func executeCheck(ctx context.Context, method probeCallable, c container.ContainerInterface, probe *canaryv1.Probe) (passed bool, output string, attempts int, err error) {
 	time.Sleep(time.Duration(probe.InitialDelaySeconds) * time.Second)
 	passes := 0
 	fails := 0
 	start := time.Now()
 	for {
-		passFail, err := method(c, probe)
+		_, span := tracer.Start(ctx, "attempt", trace.WithAttributes(attribute.Int("canary.check.attempt", attempts+1)))
+		passFail, out, err := method(c, probe)
+		span.SetAttributes(attribute.Bool("canary.check.passed", passFail))
+		endSpan(span, err)
+		attempts += 1
 		if err != nil {
-			return false, err
+			return false, out, attempts, err
 		}
 		if passFail {
 			passes += 1
@@ -196,24 +504,73 @@ func executeCheck(method probeCallable, c container.ContainerInterface, probe *c
 			passes = 0
 		}
 		if passes >= probe.SuccessThreshold || fails >= probe.FailureThreshold {
-			return passFail, err
+			return passFail, out, attempts, err
 		}
 		if time.Since(start) > time.Duration(probe.TimeoutSeconds)*time.Second {
-			return false, fmt.Errorf("check timed out after %d seconds", probe.TimeoutSeconds)
+			return false, out, attempts, exitcode.Errorf(exitcode.Timeout, "check timed out after %d seconds", probe.TimeoutSeconds)
 		}
 		time.Sleep(time.Duration(probe.PeriodSeconds) * time.Second)
 	}
 }
 
-func getStatus(check bool, err error) string {
+// endSpan marks a span as failed if there was an error and ends it
+func endSpan(span trace.Span, err error) {
 	if err != nil {
-		return failedStyle(fmt.Sprintf("error - %s", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// statusText describes the outcome of a check
+func statusText(check bool, err error) string {
+	if err != nil {
+		return fmt.Sprintf("error - %s", err.Error())
 	} else {
 		if check {
-			return passedStyle("passed")
+			return "passed"
 		} else {
-			return failedStyle("failed")
+			return "failed"
 		}
 
 	}
 }
+
+// resultStatus describes the outcome of a check, showing checks which didn't
+// pass but aren't errors by their severity
+func resultStatus(result checkResult) string {
+	if result.Passed || result.failed() {
+		return statusText(result.Passed, result.Error)
+	}
+	if result.Error != nil {
+		return fmt.Sprintf("%s - %s", result.Severity, result.Error.Error())
+	}
+	return result.Severity
+}
+
+func getStatus(result checkResult) string {
+	switch {
+	case result.Passed && result.Error == nil:
+		return passedStyle(resultStatus(result))
+	case result.Severity == canaryv1.SeverityWarning:
+		return warningStyle(resultStatus(result))
+	case result.Severity == canaryv1.SeverityInfo:
+		return highlightStyle(resultStatus(result))
+	default:
+		return failedStyle(resultStatus(result))
+	}
+}
+
+// isWarning reports whether a result counts towards the warnings in the summary
+func isWarning(result checkResult) bool {
+	return !result.Passed && result.Severity == canaryv1.SeverityWarning
+}
+
+// Indent captured output so it reads as part of the check above it
+func indentOutput(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "    " + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,435 @@
+/*
+* SPDX-FileCopyrightText: Copyright (c) <2022> NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+* SPDX-License-Identifier: Apache-2.0
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+* http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	canaryv1 "github.com/nvidia/container-canary/internal/apis/v1"
+	"github.com/nvidia/container-canary/internal/container"
+	"github.com/nvidia/container-canary/internal/exitcode"
+	"github.com/nvidia/container-canary/internal/report"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const testManifest = `apiVersion: container-canary.nvidia.com/v1
+kind: Validator
+name: example
+checks:
+  - name: shell
+    description: Has a shell
+    probe:
+      exec:
+        command: ["/bin/sh", "-c", "true"]
+  - name: bash
+    description: Has bash
+    tags: [shells, optional]
+    probe:
+      exec:
+        command: ["/bin/bash", "-c", "true"]
+`
+
+// recordingReporter keeps a log of the events it is told about
+type recordingReporter struct {
+	events []string
+}
+
+func (r *recordingReporter) configLoaded(validator *canaryv1.Validator) {
+	r.events = append(r.events, "configLoaded "+validator.Name)
+}
+
+func (r *recordingReporter) containerStarted(image string, validator *canaryv1.Validator, info *container.ContainerInfo, debug bool) {
+	r.events = append(r.events, "containerStarted "+image)
+}
+
+func (r *recordingReporter) checkSkipped(check canaryv1.Check, reason string) {
+	r.events = append(r.events, fmt.Sprintf("checkSkipped %s %s", check.Name, reason))
+}
+
+func (r *recordingReporter) checkCompleted(result checkResult, completed int, total int) {
+	r.events = append(r.events, fmt.Sprintf("checkCompleted %d/%d", completed, total))
+}
+
+func (r *recordingReporter) diagnosticsCollected(d *report.Diagnostics) {
+	r.events = append(r.events, "diagnosticsCollected "+d.Status)
+}
+
+func (r *recordingReporter) validationFinished(passed bool, containerKept bool) {
+	r.events = append(r.events, fmt.Sprintf("validationFinished %t %t", passed, containerKept))
+}
+
+func (r *recordingReporter) validationFailed(err error) {
+	r.events = append(r.events, "validationFailed "+err.Error())
+}
+
+func (r *recordingReporter) close() {}
+
+func writeManifest(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "validator.yaml")
+	if err := os.WriteFile(path, []byte(testManifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestValidation(t *testing.T, c *fakeContainer, debug bool) *validation {
+	v := newValidation("example:latest", writeManifest(t), 10, debug, false)
+	v.newContainer = func(string, *canaryv1.Validator) container.ContainerInterface { return c }
+	return v
+}
+
+func TestValidationRun(t *testing.T) {
+	assert := assert.New(t)
+	c := &fakeContainer{
+		info: container.ContainerInfo{RunCommand: "docker run example:latest", State: container.ContainerState{Status: "running", Running: true}},
+		exec: map[string]string{"/bin/sh -c true": ""},
+		logs: "listening on :8080\n",
+	}
+	v := newTestValidation(t, c, false)
+	r := &recordingReporter{}
+
+	v.run(context.Background(), r)
+	assert.Nil(v.err)
+	assert.False(v.allChecksPassed)
+	assert.True(c.removed)
+	assert.Equal([]string{
+		"configLoaded example",
+		"containerStarted example:latest",
+		"checkCompleted 1/2",
+		"checkCompleted 2/2",
+		"diagnosticsCollected running",
+		"validationFinished false false",
+	}, r.events)
+
+	rep := v.report()
+	assert.False(rep.Passed)
+	assert.Equal(&report.Diagnostics{RunCommand: "docker run example:latest", Status: "running", Logs: "listening on :8080\n"}, rep.Diagnostics)
+	assert.Equal("example", rep.Validator.Name)
+	if assert.Len(rep.Checks, 2) {
+		assert.Equal(report.StatusPassed, rep.Checks[0].Status)
+		assert.Equal(report.StatusFailed, rep.Checks[1].Status)
+	}
+}
+
+var (
+	spanExporter    = tracetest.NewInMemoryExporter()
+	installExporter sync.Once
+)
+
+// recordSpans collects the spans a test makes. The package tracer only ever
+// delegates to the first global provider, so every test shares one exporter.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	installExporter.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanExporter)))
+	})
+	spanExporter.Reset()
+	t.Cleanup(spanExporter.Reset)
+	return spanExporter
+}
+
+func TestValidationTracing(t *testing.T) {
+	assert := assert.New(t)
+	exporter := recordSpans(t)
+
+	c := &fakeContainer{exec: map[string]string{"/bin/sh -c true": ""}}
+	v := newTestValidation(t, c, false)
+	v.run(context.Background(), &recordingReporter{})
+
+	names := map[string]int{}
+	for _, span := range exporter.GetSpans() {
+		names[span.Name]++
+	}
+	assert.Equal(map[string]int{"loadConfig": 1, "startContainer": 1, "check": 2, "attempt": 2, "shutdown": 1}, names)
+}
+
+func TestValidateTracesOneRun(t *testing.T) {
+	assert := assert.New(t)
+	exporter := recordSpans(t)
+	previousImageAvailable := imageAvailable
+	imageAvailable = func(*cobra.Command, string) bool { return true }
+	t.Cleanup(func() {
+		imageAvailable = previousImageAvailable
+	})
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	cmd.SetErr(new(strings.Builder))
+	cmd.Flags().Int("startup-timeout", 1, "")
+	cmd.Flags().Bool("arbitrary-uid", false, "")
+	cmd.Flags().String("reporter", "plain", "")
+	cmd.Flags().StringArray("values", nil, "")
+	cmd.Flags().StringArray("set", nil, "")
+	cmd.Flags().String("public-key", "", "")
+	cmd.Flags().StringSlice("only", nil, "")
+	cmd.Flags().StringSlice("skip", nil, "")
+	cmd.Flags().Bool("strict", false, "")
+
+	// The container can't start as the image name is invalid, but pulling the
+	// image and loading the manifest are still traced as part of the run
+	_, err := Validate("INVALID", writeManifest(t), cmd, false)
+	assert.NotNil(err)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range exporter.GetSpans().Snapshots() {
+		spans[span.Name()] = span
+	}
+	if assert.Contains(spans, "validate") && assert.Contains(spans, "pullImage") && assert.Contains(spans, "loadConfig") {
+		traceID := spans["validate"].SpanContext().TraceID()
+		assert.Equal(traceID, spans["pullImage"].SpanContext().TraceID())
+		assert.Equal(traceID, spans["loadConfig"].SpanContext().TraceID())
+		assert.Equal(spans["validate"].SpanContext().SpanID(), spans["pullImage"].Parent().SpanID())
+	}
+}
+
+func TestValidationKeepsContainerForDebugging(t *testing.T) {
+	assert := assert.New(t)
+	c := &fakeContainer{}
+	v := newTestValidation(t, c, true)
+	r := &recordingReporter{}
+
+	v.run(context.Background(), r)
+	assert.True(v.containerKept)
+	assert.False(c.removed)
+	assert.Equal("validationFinished false true", r.events[len(r.events)-1])
+}
+
+func TestValidationArbitraryUser(t *testing.T) {
+	assert := assert.New(t)
+	c := &fakeContainer{
+		info: container.ContainerInfo{
+			Name:   "/canary-runner-1234",
+			Config: container.ContainerConfig{User: "1000650000:0"},
+			State:  container.ContainerState{Status: "running", Running: true},
+		},
+		exec: map[string]string{"/bin/sh -c true": ""},
+	}
+	v := newValidation("example:latest", writeManifest(t), 10, false, true)
+	v.newContainer = func(string, *canaryv1.Validator) container.ContainerInterface { return c }
+
+	v.run(context.Background(), &recordingReporter{})
+	rep := v.report()
+	if assert.NotNil(rep.Container) {
+		assert.Equal("canary-runner-1234", rep.Container.Name)
+		assert.Equal("1000650000:0", rep.Container.User)
+	}
+
+	// The user the image runs as isn't recorded when it isn't arbitrary
+	v = newTestValidation(t, c, false)
+	v.run(context.Background(), &recordingReporter{})
+	assert.Equal("", v.report().Container.User)
+}
+
+func TestValidationStartFailure(t *testing.T) {
+	assert := assert.New(t)
+	startErr := &container.StartError{
+		Err: errors.New("container failed to start, it exited with code 1"),
+		Diagnostics: &container.Diagnostics{
+			RunCommand: "docker run example:latest",
+			State:      container.ContainerState{Status: "exited", ExitCode: 1},
+			Logs:       "python: can't open file 'app.py'\n",
+			Writes:     []container.FileChange{{Kind: "A", Path: "/app/cache"}},
+		},
+	}
+	v := newTestValidation(t, &fakeContainer{startErr: startErr}, false)
+	r := &recordingReporter{}
+
+	v.run(context.Background(), r)
+	assert.Equal(startErr, v.err)
+	assert.Equal([]string{
+		"configLoaded example",
+		"diagnosticsCollected exited",
+		"validationFailed container failed to start, it exited with code 1",
+	}, r.events)
+
+	rep := v.report()
+	assert.False(rep.Passed)
+	assert.Equal("container failed to start, it exited with code 1", rep.Error)
+	if assert.NotNil(rep.Diagnostics) && assert.NotNil(rep.Diagnostics.ExitCode) {
+		assert.Equal(1, *rep.Diagnostics.ExitCode)
+		assert.Equal("python: can't open file 'app.py'\n", rep.Diagnostics.Logs)
+		assert.Equal([]string{"A /app/cache"}, rep.Diagnostics.Writes)
+	}
+	for _, check := range rep.Checks {
+		assert.Equal(report.StatusSkipped, check.Status)
+	}
+}
+
+func TestValidationCancelled(t *testing.T) {
+	assert := assert.New(t)
+	c := &fakeContainer{exec: map[string]string{"/bin/sh -c true": ""}}
+	v := newTestValidation(t, c, false)
+	r := &recordingReporter{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	v.run(ctx, r)
+	assert.False(v.allChecksPassed)
+	assert.True(c.removed)
+	assert.Equal([]string{"configLoaded example", "validationFailed validation cancelled"}, r.events)
+	assert.Equal(exitcode.Interrupted, exitcode.Of(v.err))
+	assert.False(v.report().Passed)
+}
+
+func TestValidationMissingConfig(t *testing.T) {
+	assert := assert.New(t)
+	v := newValidation("example:latest", filepath.Join(t.TempDir(), "missing.yaml"), 10, false, false)
+	r := &recordingReporter{}
+
+	v.run(context.Background(), r)
+	assert.Equal(exitcode.ConfigError, exitcode.Of(v.err))
+	assert.Len(r.events, 1)
+	assert.Nil(v.report().Container)
+}
+
+func TestTailLines(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("a\nb\n", tailLines("a\nb\n", 2))
+	assert.Equal("... 2 earlier lines omitted\nc\nd\n", tailLines("a\nb\nc\nd\n", 2))
+}
+
+func TestFailureCode(t *testing.T) {
+	assert := assert.New(t)
+	timeout := exitcode.Errorf(exitcode.Timeout, "check timed out after 30 seconds")
+	tests := []struct {
+		results []checkResult
+		code    exitcode.Code
+	}{
+		{[]checkResult{{Passed: true}, {Passed: false}}, exitcode.ChecksFailed},
+		{[]checkResult{{Error: timeout}, {Passed: false}}, exitcode.ChecksFailed},
+		{[]checkResult{{Error: errors.New("failed to inspect image")}, {Error: timeout}}, exitcode.Timeout},
+		{[]checkResult{{Passed: true}, {Error: errors.New("failed to inspect image")}}, exitcode.RuntimeError},
+	}
+	for _, test := range tests {
+		v := &validation{results: test.results}
+		assert.Equal(test.code, v.failureCode())
+	}
+}
+
+func TestValidationUnknownParameter(t *testing.T) {
+	assert := assert.New(t)
+	v := newTestValidation(t, &fakeContainer{}, false)
+	v.loadOptions.Parameters = map[string]string{"user": "alice"}
+	r := &recordingReporter{}
+
+	v.run(context.Background(), r)
+	assert.Equal(exitcode.ConfigError, exitcode.Of(v.err))
+	assert.Equal([]string{"validationFailed unknown parameter 'user'"}, r.events)
+}
+
+func TestValidationSelection(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		selection checkSelection
+		events    []string
+		statuses  []report.Status
+	}{
+		{
+			checkSelection{skip: []string{"optional"}},
+			[]string{"checkSkipped bash by --skip optional", "checkCompleted 1/1", "validationFinished true false"},
+			[]report.Status{report.StatusPassed, report.StatusSkipped},
+		},
+		{
+			checkSelection{only: []string{"shell"}},
+			[]string{"checkSkipped bash not selected by --only shell", "checkCompleted 1/1", "validationFinished true false"},
+			[]report.Status{report.StatusPassed, report.StatusSkipped},
+		},
+		{
+			checkSelection{only: []string{"shell", "shells"}, skip: []string{"bash"}},
+			[]string{"checkSkipped bash by --skip bash", "checkCompleted 1/1", "validationFinished true false"},
+			[]report.Status{report.StatusPassed, report.StatusSkipped},
+		},
+		{
+			checkSelection{only: []string{"shells"}},
+			[]string{"checkSkipped shell not selected by --only shells", "checkCompleted 1/1", "diagnosticsCollected ", "validationFinished false false"},
+			[]report.Status{report.StatusSkipped, report.StatusFailed},
+		},
+	}
+	for _, test := range tests {
+		c := &fakeContainer{exec: map[string]string{"/bin/sh -c true": ""}}
+		v := newTestValidation(t, c, false)
+		v.selection = test.selection
+		r := &recordingReporter{}
+
+		v.run(context.Background(), r)
+		assert.Nil(v.err)
+		assert.Equal(test.events, r.events[2:])
+		rep := v.report()
+		for i, status := range test.statuses {
+			assert.Equal(status, rep.Checks[i].Status)
+		}
+		assert.Equal(v.selection.skipReason(v.validator.Checks[1]), rep.Checks[1].Reason)
+	}
+}
+
+func TestValidationSelectionErrors(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		selection checkSelection
+		err       string
+	}{
+		{checkSelection{only: []string{"shel"}}, "--only shel matches no check names or tags in example"},
+		{checkSelection{skip: []string{"slow"}}, "--skip slow matches no check names or tags in example"},
+		{checkSelection{skip: []string{"shell", "shells"}}, "no checks selected to run, --only and --skip exclude all of them"},
+	}
+	for _, test := range tests {
+		v := newTestValidation(t, &fakeContainer{}, false)
+		v.selection = test.selection
+		r := &recordingReporter{}
+
+		v.run(context.Background(), r)
+		assert.Equal(exitcode.ConfigError, exitcode.Of(v.err))
+		assert.Equal([]string{"validationFailed " + test.err}, r.events)
+	}
+}
+
+func TestValidationSeverity(t *testing.T) {
+	assert := assert.New(t)
+	for _, strict := range []bool{false, true} {
+		c := &fakeContainer{exec: map[string]string{"/bin/sh -c true": ""}}
+		v := newTestValidation(t, c, false)
+		manifest := strings.Replace(testManifest, "tags: [shells, optional]", "tags: [shells, optional]\n    severity: warning", 1)
+		assert.Nil(os.WriteFile(v.configPath, []byte(manifest), 0o644))
+		v.strict = strict
+		r := &recordingReporter{}
+
+		v.run(context.Background(), r)
+		assert.Nil(v.err)
+		assert.Equal(!strict, v.allChecksPassed)
+		rep := v.report()
+		assert.Equal(!strict, rep.Passed)
+		assert.Equal(report.StatusFailed, rep.Checks[1].Status)
+		if strict {
+			assert.Equal(canaryv1.SeverityError, rep.Checks[1].Severity)
+		} else {
+			assert.Equal(canaryv1.SeverityWarning, rep.Checks[1].Severity)
+			assert.Equal("validationFinished true false", r.events[len(r.events)-1])
+		}
+		assert.Equal(canaryv1.SeverityError, rep.Checks[0].Severity)
+	}
+}